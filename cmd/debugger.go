@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// debugBuildFlags turn off optimizations and inlining so every variable and
+// line can be inspected.
+const debugBuildFlags = "-gcflags=all=-N -l"
+
+// debuggerRPCTimeout bounds each call made to Delve while the app restarts.
+const debuggerRPCTimeout = 2 * time.Second
+
+// debuggerPollInterval is how often the app is checked for an exit while it
+// runs under Delve.
+const debuggerPollInterval = 500 * time.Millisecond
+
+// debugger runs the app under a headless Delve server that editors attach to.
+// The breakpoints are read back before each restart and set again on the new
+// process, so they survive rebuilds.
+type debugger struct {
+	dlv         string
+	address     string
+	mutex       sync.Mutex
+	breakpoints []delveBreakpoint
+	appExit     error
+}
+
+// delveBreakpoint holds the fields of a Delve breakpoint that still apply once
+// the app is rebuilt, the addresses change with each build.
+type delveBreakpoint struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+	Cond        string
+	HitCond     string
+	HitCondPerG bool
+	Tracepoint  bool     `json:"continue"`
+	TraceReturn bool     `json:"traceReturn"`
+	Goroutine   bool     `json:"goroutine"`
+	Stacktrace  int      `json:"stacktrace"`
+	Variables   []string `json:"variables,omitempty"`
+	Disabled    bool     `json:"disabled"`
+	WatchExpr   string
+}
+
+type delveState struct {
+	Exited     bool `json:"exited"`
+	ExitStatus int  `json:"exitStatus"`
+}
+
+type delveCommand struct {
+	Name string `json:"name"`
+}
+
+type delveCommandOut struct {
+	State delveState
+}
+
+type delveStateIn struct {
+	NonBlocking bool
+}
+
+type delveStateOut struct {
+	State *delveState
+}
+
+type delveListBreakpointsIn struct {
+	All bool
+}
+
+type delveListBreakpointsOut struct {
+	Breakpoints []delveBreakpoint
+}
+
+type delveCreateBreakpointIn struct {
+	Breakpoint delveBreakpoint
+}
+
+type delveCreateBreakpointOut struct {
+	Breakpoint delveBreakpoint
+}
+
+func newDebugger(port int) (*debugger, error) {
+	dlv, err := exec.LookPath("dlv")
+	if err != nil {
+		return nil, fmt.Errorf("--debug needs Delve, install it with go install github.com/go-delve/delve/cmd/dlv@latest: %w", err)
+	}
+	return &debugger{
+		dlv:     dlv,
+		address: fmt.Sprintf("127.0.0.1:%d", port),
+	}, nil
+}
+
+// command starts binary under Delve, halted until attach sets the breakpoints.
+func (d *debugger) command(ctx context.Context, binary string, output io.Writer) *exec.Cmd {
+	runCmd := exec.CommandContext(ctx, d.dlv, "exec", binary,
+		"--headless",
+		"--listen="+d.address,
+		"--api-version=2",
+		"--accept-multiclient",
+	)
+	runCmd.Stdout = os.Stdout
+	runCmd.Stderr = io.MultiWriter(os.Stderr, output)
+	setProcessGroup(runCmd)
+	// Delve kills the app when it stops, keep the breakpoints first
+	runCmd.Cancel = func() error {
+		d.saveBreakpoints()
+		return terminateProcessTree(runCmd)
+	}
+	runCmd.WaitDelay = 15 * time.Second
+	return runCmd
+}
+
+// attach connects to the Delve server started by runCmd, sets the saved
+// breakpoints and resumes the app. Delve keeps running for new clients once
+// the app exits, attach stops it then.
+func (d *debugger) attach(ctx context.Context, runCmd *exec.Cmd, exited <-chan struct{}) {
+	d.mutex.Lock()
+	d.appExit = nil
+	d.mutex.Unlock()
+
+	client, err := d.dial(ctx, exited)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("error attaching to the debugger: %v", err)
+		}
+		return
+	}
+	defer client.Close()
+	d.restoreBreakpoints(client)
+	// Continue only returns when the app stops, its state is polled instead
+	client.Go("RPCServer.Command", delveCommand{Name: "continue"}, &delveCommandOut{}, nil)
+	log.Printf("Debugger listening on %s", d.address)
+
+	ticker := time.NewTicker(debuggerPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-exited:
+			return
+		case <-ticker.C:
+		}
+		var out delveStateOut
+		err := client.Call("RPCServer.State", delveStateIn{NonBlocking: true}, &out)
+		if ctx.Err() != nil {
+			return
+		}
+		// State fails once the app exited, with its exit status. Other errors
+		// mean Delve itself went away
+		var exitErr rpc.ServerError
+		if err != nil && !errors.As(err, &exitErr) {
+			return
+		}
+		if err == nil && !out.State.Exited {
+			continue
+		}
+		if err == nil {
+			err = fmt.Errorf("exit status %d", out.State.ExitStatus)
+		}
+		d.mutex.Lock()
+		d.appExit = err
+		d.mutex.Unlock()
+		d.listBreakpoints(client)
+		terminateProcessTree(runCmd)
+		return
+	}
+}
+
+// exitError explains why the app stopped, Delve itself exits cleanly.
+func (d *debugger) exitError(runErr error) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.appExit != nil {
+		return d.appExit
+	}
+	return runErr
+}
+
+// dial waits for the Delve server to listen, until it exits.
+func (d *debugger) dial(ctx context.Context, exited <-chan struct{}) (*rpc.Client, error) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		conn, err := net.DialTimeout("tcp", d.address, debuggerRPCTimeout)
+		if err == nil {
+			return jsonrpc.NewClient(conn), nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-exited:
+			return nil, errors.New("delve exited before listening")
+		case <-ticker.C:
+		}
+	}
+}
+
+// saveBreakpoints halts the app and keeps its breakpoints for the next run.
+func (d *debugger) saveBreakpoints() {
+	conn, err := net.DialTimeout("tcp", d.address, debuggerRPCTimeout)
+	if err != nil {
+		return
+	}
+	client := jsonrpc.NewClient(conn)
+	defer client.Close()
+	conn.SetDeadline(time.Now().Add(debuggerRPCTimeout))
+	// Breakpoints can only be listed while the app is stopped
+	client.Call("RPCServer.Command", delveCommand{Name: "halt"}, &delveCommandOut{})
+	d.listBreakpoints(client)
+}
+
+func (d *debugger) listBreakpoints(client *rpc.Client) {
+	var out delveListBreakpointsOut
+	if err := client.Call("RPCServer.ListBreakpoints", delveListBreakpointsIn{}, &out); err != nil {
+		return
+	}
+	breakpoints := make([]delveBreakpoint, 0, len(out.Breakpoints))
+	for _, breakpoint := range out.Breakpoints {
+		// Delve creates the negative ids itself, watchpoints only make sense
+		// in the process that set them
+		if breakpoint.ID <= 0 || breakpoint.File == "" || breakpoint.WatchExpr != "" {
+			continue
+		}
+		breakpoints = append(breakpoints, breakpoint)
+	}
+	d.mutex.Lock()
+	d.breakpoints = breakpoints
+	d.mutex.Unlock()
+}
+
+// restoreBreakpoints sets the saved breakpoints again by file and line.
+func (d *debugger) restoreBreakpoints(client *rpc.Client) {
+	d.mutex.Lock()
+	breakpoints := d.breakpoints
+	d.mutex.Unlock()
+	for _, breakpoint := range breakpoints {
+		breakpoint.ID = 0
+		if err := client.Call("RPCServer.CreateBreakpoint", delveCreateBreakpointIn{Breakpoint: breakpoint}, &delveCreateBreakpointOut{}); err != nil {
+			log.Printf("Breakpoint %s:%d dropped: %v", breakpoint.File, breakpoint.Line, err)
+		}
+	}
+}
@@ -4,8 +4,11 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
+	"strings"
 
 	gothic_cli "github.com/felipegenef/gothicframework/pkg/cli"
 	"github.com/felipegenef/gothicframework/pkg/helpers"
@@ -69,10 +72,21 @@ func newDeployCommand(cli gothic_cli.GothicCli) RunEFunc {
 	}
 }
 
+// runtimeConfigFile is the gothic-config.json embedded by the Dockerfile.
+const runtimeConfigFile = ".gothicCli/runtime-config.json"
+
 func (command *DeployCommand) Deploy(stage string, action string) error {
-	if err := command.setup(stage); err != nil {
-		return err
+	var parameters []string
+	if action == "deploy" {
+		secret, err := command.sessionSecret(stage)
+		if err != nil {
+			return err
+		}
+		if secret != "" {
+			parameters = append(parameters, "SessionSecret="+secret)
+		}
 	}
+
 	if err := command.cli.Templ.Render(); err != nil {
 		return err
 	}
@@ -81,6 +95,11 @@ func (command *DeployCommand) Deploy(stage string, action string) error {
 		return err
 	}
 
+	// Routes must be rendered first so their cache keys reach the SAM template
+	if err := command.setup(stage); err != nil {
+		return err
+	}
+
 	if err := command.cli.Tailwind.Build(); err != nil {
 		return err
 	}
@@ -99,7 +118,7 @@ func (command *DeployCommand) Deploy(stage string, action string) error {
 
 	switch action {
 	case "deploy":
-		if err := command.cli.AwsSam.Deploy(stage, config.ProjectName, config.Deploy.Profile); err != nil {
+		if err := command.cli.AwsSam.Deploy(stage, config.ProjectName, config.Deploy.Profile, parameters...); err != nil {
 			return err
 		}
 
@@ -117,6 +136,48 @@ func (command *DeployCommand) Deploy(stage string, action string) error {
 	return nil
 }
 
+// sessionSecret returns the SESSION_SECRET passed to the Lambda as a NoEcho
+// parameter, or an empty string when sessions are disabled. It is never read
+// from gothic-config.json: the sessionSecretParameter of the stage names the
+// SSM parameter holding it, otherwise it comes from the deploying shell.
+func (command *DeployCommand) sessionSecret(stage string) (string, error) {
+	config := command.cli.GetConfig()
+	if !config.Sessions.Enabled || config.Deploy == nil {
+		return "", nil
+	}
+	secret := os.Getenv("SESSION_SECRET")
+	if parameter := config.Deploy.Stages[stage].SessionSecretParameter; parameter != nil && *parameter != "" {
+		var err error
+		if secret, err = command.cli.AWS.GetSecureParameter(*parameter, config.Deploy.Region, config.Deploy.Profile); err != nil {
+			return "", err
+		}
+	}
+	if len(secret) < 32 {
+		return "", fmt.Errorf(`sessions are enabled but stage %s has no SESSION_SECRET of at least 32 characters: export it before deploying or set "sessionSecretParameter" to the SSM parameter holding it. Set "enabled" to false in the "sessions" section if the app doesn't use sessions`, stage)
+	}
+	return secret, nil
+}
+
+// writeRuntimeConfig copies gothic-config.json to runtimeConfigFile without
+// its "deploy" section: the app never reads it, so its stage settings stay out
+// of the binary.
+func (command *DeployCommand) writeRuntimeConfig() error {
+	content, err := os.ReadFile("gothic-config.json")
+	if err != nil {
+		return fmt.Errorf("error reading gothic-config.json: %v", err)
+	}
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(content, &sections); err != nil {
+		return fmt.Errorf("error decoding gothic-config.json: %v", err)
+	}
+	delete(sections, "deploy")
+	runtimeConfig, err := json.MarshalIndent(sections, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %v", runtimeConfigFile, err)
+	}
+	return os.WriteFile(runtimeConfigFile, runtimeConfig, 0644)
+}
+
 func (command *DeployCommand) setup(stage string) error {
 	config := command.cli.GetConfig()
 
@@ -143,6 +204,8 @@ func (command *DeployCommand) setup(stage string) error {
 	yamlInfo.Timeout = config.Deploy.ServerTimeout
 	yamlInfo.MemorySize = config.Deploy.ServerMemory
 	yamlInfo.ProjectName = config.ProjectName
+	yamlInfo.AppID = appID
+	yamlInfo.GitSHA = command.gitSHA()
 	yamlInfo.StageTemplateInfo.Name = stage
 	yamlInfo.StageTemplateInfo.BucketName = `BucketName: "` + envConfig.BucketName + `"`
 	yamlInfo.StageTemplateInfo.LambdaName = `LambdaName: "` + envConfig.LambdaName + `"`
@@ -152,6 +215,21 @@ func (command *DeployCommand) setup(stage string) error {
 	yamlInfo.StageTemplateInfo.IsCustomDomain = false
 	yamlInfo.StageTemplateInfo.IsCustomDomainWithArn = false
 	yamlInfo.UsedTemplateName = ".gothicCli/templates/sam-template.yaml"
+	yamlInfo.CachePolicy = command.cli.FileBasedRouter.CachePolicy()
+	// CORS responses differ per Origin, the edge must not share them
+	if config.CORS.Enabled() || command.cli.FileBasedRouter.UsesCORS() {
+		yamlInfo.CachePolicy.AddHeader("Origin")
+	}
+	yamlInfo.EnableAcceptEncodingGzip = config.Compression.Accepts("gzip")
+	yamlInfo.EnableAcceptEncodingBrotli = config.Compression.Accepts("br")
+	if err := config.SecurityHeaders.Validate(); err != nil {
+		return err
+	}
+	if err := config.CORS.Validate(); err != nil {
+		return err
+	}
+	yamlInfo.SecurityHeaders = config.SecurityHeaders
+	yamlInfo.Sessions = config.Sessions.Enabled
 
 	var env []helpers.EnvValueInfo
 
@@ -197,6 +275,9 @@ func (command *DeployCommand) setup(stage string) error {
 	command.cli.Templates.CopyFile(yamlInfo.UsedTemplateName, "template.yaml")
 	command.cli.Templates.UpdateFromTemplate("template.yaml", "template.yaml", yamlInfo)
 	command.cli.Templates.CopyFile(".gothicCli/templates/Dockerfile-template", "Dockerfile")
+	if err := command.writeRuntimeConfig(); err != nil {
+		return err
+	}
 	command.cli.Templates.CopyFile(".gothicCli/templates/samconfig-template.toml", "samconfig.toml")
 	// Replace the region
 	command.cli.Templates.UpdateFromTemplate("samconfig.toml", "samconfig.toml", helpers.SamTomlTemplateInfo{
@@ -212,6 +293,7 @@ func (command *DeployCommand) cleanup() {
 		"Dockerfile",
 		"template.yaml",
 		"samconfig.toml",
+		runtimeConfigFile,
 	}
 
 	// Iterate over each file and attempt to delete it
@@ -224,6 +306,16 @@ func (command *DeployCommand) cleanup() {
 	}
 }
 
+// gitSHA returns the deployed commit exposed by the version endpoint, or an
+// empty string outside a git repository.
+func (command *DeployCommand) gitSHA() string {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
 func (command *DeployCommand) isValidAction(c string) bool {
 	for _, a := range command.allowedActions {
 		if a == c {
@@ -4,22 +4,35 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	gothic_cli "github.com/felipegenef/gothicframework/pkg/cli"
+	"github.com/felipegenef/gothicframework/pkg/helpers/proxy"
 	"github.com/fsnotify/fsnotify"
 	"github.com/joho/godotenv"
+	"github.com/skip2/go-qrcode"
 	"github.com/spf13/cobra"
 )
 
@@ -34,48 +47,226 @@ It allows you to develop and debug your Gothic app more efficiently, with change
 
 func init() {
 	rootCmd.AddCommand(hotReloadCmd)
+	defaults := gothic_cli.DefaultDevServerConfig
+	hotReloadCmd.Flags().String("host", defaults.Host, "Address the proxy binds to, --host alone opens it to the devices of your network")
+	hotReloadCmd.Flags().Lookup("host").NoOptDefVal = "0.0.0.0"
+	hotReloadCmd.Flags().IntP("port", "p", defaults.ProxyPort, "Port of the hot reload proxy opened in the browser")
+	hotReloadCmd.Flags().Int("app-port", defaults.AppPort, "Port of the app behind the proxy, defaults to HTTP_LISTEN_ADDR or 8080")
+	hotReloadCmd.Flags().StringSlice("watch", defaults.WatchDirs, "Directories watched for changes")
+	hotReloadCmd.Flags().StringSlice("exclude", defaults.ExcludedDirs, "Directory names never watched")
+	hotReloadCmd.Flags().StringSlice("layouts", defaults.LayoutDirs, "Directories whose templ changes reload the whole page")
+	hotReloadCmd.Flags().StringSlice("ext", defaults.WatchedExtensions, "File extensions that trigger a rebuild")
+	hotReloadCmd.Flags().String("binary", defaults.Binary, "Path of the built app")
+	hotReloadCmd.Flags().StringArray("tailwind-arg", defaults.TailwindArgs, "Argument passed to Tailwind, repeat for each one")
+	hotReloadCmd.Flags().String("ready-path", defaults.ReadyPath, "Path polled until the restarted app answers, empty waits for its port")
+	hotReloadCmd.Flags().StringArray("pre-rebuild", defaults.PreRebuild, "Shell command run before each build, repeat for each one")
+	hotReloadCmd.Flags().StringArray("post-rebuild", defaults.PostRebuild, "Shell command run after each restart, repeat for each one")
+	hotReloadCmd.Flags().Bool("no-browser", false, "Don't open the browser, for headless environments")
+	hotReloadCmd.Flags().Bool("debug", false, "Build without optimizations and run the app under a headless Delve server")
+	hotReloadCmd.Flags().Int("debug-port", defaults.DebugPort, "Port Delve listens on with --debug")
+	hotReloadCmd.Flags().Bool("https", defaults.HTTPS, "Serve the proxy over HTTPS with a certificate signed by a local CA")
 }
 
+// cssChangeDelay is how long stylesheets must stay unchanged before the
+// browser is told to fetch them again.
+const cssChangeDelay = 100 * time.Millisecond
+
+// rebuildDelay is how long source files must stay unchanged before the app
+// is rebuilt.
+const rebuildDelay = 200 * time.Millisecond
+
+// crashOutputSize is how much of the app error output is kept to explain a
+// crash.
+const crashOutputSize = 16 * 1024
+
+// A crashed app is restarted after restartMinDelay, doubled after each crash
+// up to restartMaxDelay. Runs longer than stableRunTime reset the delay.
+const (
+	restartMinDelay = 500 * time.Millisecond
+	restartMaxDelay = 30 * time.Second
+	stableRunTime   = time.Minute
+)
+
+// proxyShutdownTimeout bounds the wait for requests still going through the
+// proxy when hot reload stops.
+const proxyShutdownTimeout = 5 * time.Second
+
+// certificateDir caches the local CA and the proxy certificate of --https.
+var certificateDir = filepath.Join(".gothicCli", "certs")
+
+// tailwindReadyTimeout bounds the wait for the first Tailwind output.
+const tailwindReadyTimeout = 30 * time.Second
+
 type HotReloadCommand struct {
 	cli               *gothic_cli.GothicCli
 	tailwindFile      string
+	tailwindArgs      []string
 	mainBinaryName    string
-	runCmd            *exec.Cmd
+	host              string
+	proxyPort         int
+	appPort           int
+	appURL            *url.URL
+	readyPath         string
+	readyTimeout      time.Duration
 	runCancel         context.CancelFunc
+	runDone           chan struct{}
 	mutex             sync.Mutex
+	queueMutex        sync.Mutex
+	buildCancel       context.CancelFunc
+	queuedChanges     []string
+	queuedFullReload  bool
+	stopped           bool
+	quit              chan struct{}
+	quitOnce          sync.Once
+	tailwindDone      chan struct{}
+	noBrowser         bool
+	https             bool
+	certificate       proxy.LocalCertificate
+	debugger          *debugger
+	watchDirs         []string
 	excludedDirs      []string
+	layoutDirs        []string
 	watchedExtensions []string
+	preRebuild        []string
+	postRebuild       []string
 	excludeRegex      regexp.Regexp
 }
 
-func newHotReloadCommandCli(cli *gothic_cli.GothicCli) HotReloadCommand {
+func newHotReloadCommandCli(cli *gothic_cli.GothicCli, config gothic_cli.DevServerConfig) HotReloadCommand {
 	var tailwindBinary string = "./tailwindcss"
-	var mainBinary string = "tmp/main"
+	var mainBinary string = config.Binary
 	if runtime.GOOS == "windows" {
 		tailwindBinary = "./tailwindcss.exe"
-		mainBinary = "tmp/main.exe"
+		if filepath.Ext(mainBinary) != ".exe" {
+			mainBinary += ".exe"
+		}
+	}
+	layoutDirs := make([]string, 0, len(config.LayoutDirs))
+	for _, dir := range config.LayoutDirs {
+		layoutDirs = append(layoutDirs, filepath.Clean(dir))
 	}
 	return HotReloadCommand{
 		cli:               cli,
 		tailwindFile:      tailwindBinary,
+		tailwindArgs:      config.TailwindArgs,
 		mainBinaryName:    mainBinary,
-		excludedDirs:      []string{"assets", "tmp", "vendor", "public", "routes"},
-		watchedExtensions: []string{".go", ".tpl", ".tmpl", ".templ", ".html"},
+		host:              config.Host,
+		proxyPort:         config.ProxyPort,
+		https:             config.HTTPS,
+		appPort:           config.AppPort,
+		readyPath:         config.ReadyPath,
+		readyTimeout:      time.Duration(config.ReadyTimeoutInSec) * time.Second,
+		watchDirs:         config.WatchDirs,
+		excludedDirs:      config.ExcludedDirs,
+		layoutDirs:        layoutDirs,
+		watchedExtensions: config.WatchedExtensions,
+		preRebuild:        config.PreRebuild,
+		postRebuild:       config.PostRebuild,
 		excludeRegex:      *regexp.MustCompile(`.*_templ\.go$`),
+		quit:              make(chan struct{}),
 	}
 }
 
 func newHotReloadCommand(cli gothic_cli.GothicCli) RunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
-		command := newHotReloadCommandCli(&cli)
+		config, err := devServerConfig(cmd, cli.GetConfig().DevServer)
+		if err != nil {
+			return err
+		}
+		command := newHotReloadCommandCli(&cli, config)
+		if command.noBrowser, err = cmd.Flags().GetBool("no-browser"); err != nil {
+			return err
+		}
+		debug, err := cmd.Flags().GetBool("debug")
+		if err != nil {
+			return err
+		}
+		if debug {
+			if command.debugger, err = newDebugger(config.DebugPort); err != nil {
+				return err
+			}
+		}
 
 		return command.HotReload()
 	}
 }
 
+// devServerConfig overrides the "devServer" section of gothic-config.json
+// with the flags set on the command line.
+func devServerConfig(cmd *cobra.Command, config gothic_cli.DevServerConfig) (gothic_cli.DevServerConfig, error) {
+	flags := cmd.Flags()
+	var err error
+	if flags.Changed("host") {
+		if config.Host, err = flags.GetString("host"); err != nil {
+			return config, err
+		}
+	}
+	if flags.Changed("port") {
+		if config.ProxyPort, err = flags.GetInt("port"); err != nil {
+			return config, err
+		}
+	}
+	if flags.Changed("app-port") {
+		if config.AppPort, err = flags.GetInt("app-port"); err != nil {
+			return config, err
+		}
+	}
+	if flags.Changed("debug-port") {
+		if config.DebugPort, err = flags.GetInt("debug-port"); err != nil {
+			return config, err
+		}
+	}
+	if flags.Changed("https") {
+		if config.HTTPS, err = flags.GetBool("https"); err != nil {
+			return config, err
+		}
+	}
+	if flags.Changed("binary") {
+		if config.Binary, err = flags.GetString("binary"); err != nil {
+			return config, err
+		}
+	}
+	if flags.Changed("ready-path") {
+		if config.ReadyPath, err = flags.GetString("ready-path"); err != nil {
+			return config, err
+		}
+	}
+	sliceFlags := map[string]*[]string{
+		"watch":   &config.WatchDirs,
+		"exclude": &config.ExcludedDirs,
+		"layouts": &config.LayoutDirs,
+		"ext":     &config.WatchedExtensions,
+	}
+	for name, field := range sliceFlags {
+		if flags.Changed(name) {
+			if *field, err = flags.GetStringSlice(name); err != nil {
+				return config, err
+			}
+		}
+	}
+	arrayFlags := map[string]*[]string{
+		"tailwind-arg": &config.TailwindArgs,
+		"pre-rebuild":  &config.PreRebuild,
+		"post-rebuild": &config.PostRebuild,
+	}
+	for name, field := range arrayFlags {
+		if flags.Changed(name) {
+			if *field, err = flags.GetStringArray(name); err != nil {
+				return config, err
+			}
+		}
+	}
+	return config, nil
+}
+
 func (command *HotReloadCommand) HotReload() error {
 	godotenv.Load()
 	port := os.Getenv("HTTP_LISTEN_ADDR")
+	if command.appPort != 0 {
+		port = fmt.Sprintf(":%d", command.appPort)
+		// The app reads its port from the environment it inherits
+		os.Setenv("HTTP_LISTEN_ADDR", port)
+	}
 	if port == "" {
 		port = ":8080"
 	}
@@ -83,11 +274,29 @@ func (command *HotReloadCommand) HotReload() error {
 	if err != nil {
 		log.Fatalf("Invalid target URL: %v", err)
 	}
-	go command.watchTailwindChanges()
-	// Wait for tailwind process to render css for the first time
-	time.Sleep(4 * time.Second)
-	go command.watchForChanges()
-	go command.cli.Proxy.RunProxy("localhost", 3000, targetURL)
+	command.appURL = targetURL
+	scheme := "http"
+	networkAddresses := command.networkAddresses()
+	if command.https {
+		hosts := append([]string{"localhost", "127.0.0.1", "::1"}, networkAddresses...)
+		if command.certificate, err = proxy.LoadLocalCertificate(certificateDir, hosts); err != nil {
+			return fmt.Errorf("error loading the HTTPS certificate: %w", err)
+		}
+		command.cli.Proxy.TLSConfig = command.certificate.TLSConfig()
+		scheme = "https"
+	}
+
+	// Children run in their own process groups and miss the terminal's Ctrl-C,
+	// they are stopped below instead
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	command.watchTailwindChanges(ctx)
+	go command.watchForChanges(ctx)
+	go command.watchPublicChanges(ctx)
+	go command.cli.Proxy.RunProxy(command.host, command.proxyPort, targetURL)
 
 	banner := `
  ██████╗  ██████╗ ████████╗██╗  ██╗██╗ ██████╗     █████╗ ██████╗ ██████╗ 
@@ -98,13 +307,78 @@ func (command *HotReloadCommand) HotReload() error {
  ╚═════╝  ╚═════╝    ╚═╝   ╚═╝  ╚═╝╚═╝ ╚═════╝    ╚═╝  ╚═╝╚═╝     ╚═╝     
 
 🚀 Gothic App is up and running!
-🌐 Listening on: http://127.0.0.1:3000
+🌐 Listening on: %s
 🔥  Mode: HOT RELOAD ENABLED
+⌨️  Type r + Enter to rebuild, q + Enter to quit
 `
-	fmt.Println(banner)
-	command.openBrowser("http://127.0.0.1:3000")
-	select {}
+	localURL := command.proxyURL(scheme, command.localAddress())
+	fmt.Printf(banner, localURL)
+	if command.https {
+		command.printTrustInstructions()
+	}
+	if command.exposed() {
+		command.printNetworkURLs(scheme, networkAddresses)
+	}
+	if command.debugger != nil {
+		fmt.Printf("🐞 Debugger: attach to %s\n", command.debugger.address)
+	}
+	if !command.noBrowser {
+		command.openBrowser(localURL)
+	}
+	go command.readShortcuts()
 
+	select {
+	case <-signalCtx.Done():
+	case <-command.quit:
+	}
+	// A second Ctrl-C exits right away
+	stop()
+	log.Println("Shutting down...")
+	command.stopApp()
+	cancel()
+	if command.tailwindDone != nil {
+		<-command.tailwindDone
+	}
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), proxyShutdownTimeout)
+	defer cancelShutdown()
+	if err := command.cli.Proxy.Shutdown(shutdownCtx); err != nil {
+		log.Printf("error shutting down proxy: %v", err)
+	}
+	return nil
+}
+
+// readShortcuts handles the keys typed in the terminal, each followed by Enter.
+func (command *HotReloadCommand) readShortcuts() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		switch strings.TrimSpace(scanner.Text()) {
+		case "r":
+			log.Println("Rebuilding...")
+			go command.scheduleRebuild(nil)
+		case "q":
+			command.quitOnce.Do(func() { close(command.quit) })
+			return
+		}
+	}
+}
+
+// stopApp aborts the build in flight and stops the app for good.
+func (command *HotReloadCommand) stopApp() {
+	command.queueMutex.Lock()
+	if command.buildCancel != nil {
+		command.buildCancel()
+	}
+	command.queueMutex.Unlock()
+
+	command.mutex.Lock()
+	defer command.mutex.Unlock()
+	command.stopped = true
+	if command.runCancel != nil {
+		log.Println("Stopping app...")
+		command.runCancel()
+		command.runCancel = nil
+		<-command.runDone
+	}
 }
 
 func (command *HotReloadCommand) isExcludedDir(path string) bool {
@@ -116,38 +390,60 @@ func (command *HotReloadCommand) isExcludedDir(path string) bool {
 	return false
 }
 
-func (command *HotReloadCommand) watchForChanges() {
-	command.rebuild()
+func (command *HotReloadCommand) watchForChanges(ctx context.Context) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		fmt.Printf("error creating watcher: %v", err)
+		fmt.Printf("error creating watcher: %v\n", err)
+		command.scheduleRebuild(nil)
+		return
 	}
 	defer watcher.Close()
-	err = filepath.Walk("src", func(path string, info os.FileInfo, err error) error {
+	for _, dir := range command.watchDirs {
+		err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() && command.isExcludedDir(path) {
+				return filepath.SkipDir
+			}
+			if info.IsDir() {
+				return watcher.Add(path)
+			}
+			return nil
+		})
 		if err != nil {
-			return err
+			break
 		}
-		if info.IsDir() && command.isExcludedDir(path) {
-			return filepath.SkipDir
-		}
-		if info.IsDir() {
-			return watcher.Add(path)
-		}
-		return nil
-	})
+	}
 	if err != nil {
 		fmt.Printf("error walking through directories: %v", err)
-		command.rebuild()
 	}
+	// Build once the directories are watched, so files saved during the first
+	// build trigger a rebuild instead of being missed
+	go command.scheduleRebuild(nil)
 
+	// Saves and checkouts emit bursts of events, rebuild once they settle
+	changed := map[string]bool{}
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
 	for {
 		select {
+		case <-ctx.Done():
+			return
+		case <-debounce.C:
+			files := make([]string, 0, len(changed))
+			for file := range changed {
+				files = append(files, file)
+			}
+			clear(changed)
+			go command.scheduleRebuild(files)
 		case event, ok := <-watcher.Events:
 			if !ok {
 				return
 			}
-			if command.shouldHandle(event.Name, event.Op) {
-				command.rebuild()
+			if event.Op != fsnotify.Chmod && command.shouldHandle(event.Name, event.Op) {
+				changed[event.Name] = true
+				debounce.Reset(rebuildDelay)
 			}
 			// Dynamically watch new directories
 			if event.Op&fsnotify.Create == fsnotify.Create {
@@ -162,10 +458,10 @@ func (command *HotReloadCommand) watchForChanges() {
 				}
 			}
 		case err, ok := <-watcher.Errors:
-			command.rebuild()
 			if !ok {
-				fmt.Printf("error reloading app: %v", err)
+				return
 			}
+			go command.scheduleRebuild(nil)
 			log.Println("Watcher error:", err)
 		}
 	}
@@ -193,53 +489,264 @@ func (command *HotReloadCommand) shouldHandle(path string, op fsnotify.Op) bool
 	return false
 }
 
-func (command *HotReloadCommand) watchTailwindChanges() {
+// watchTailwindChanges starts Tailwind in watch mode until ctx is canceled.
+// It returns once Tailwind wrote its output for the first time, or exited.
+func (command *HotReloadCommand) watchTailwindChanges(ctx context.Context) {
 	log.Println("Starting Tailwind in watch mode...")
 
-	tailWindCmd := exec.Command(command.tailwindFile, "--watch=always", "-i", "src/css/app.css", "-o", "public/styles.css", "--minify")
+	started := time.Now()
+	tailWindCmd := exec.CommandContext(ctx, command.tailwindFile, append([]string{"--watch=always"}, command.tailwindArgs...)...)
 	tailWindCmd.Stdout = os.Stdout
 	tailWindCmd.Stderr = os.Stderr
+	setProcessGroup(tailWindCmd)
+	tailWindCmd.Cancel = func() error { return terminateProcessTree(tailWindCmd) }
+	tailWindCmd.WaitDelay = 5 * time.Second
 
 	// Start the process asynchronously (non-blocking, like Node's spawn)
 	if err := tailWindCmd.Start(); err != nil {
-		fmt.Printf("Failed to start Tailwind watch process: %v", err)
+		fmt.Printf("Failed to start Tailwind watch process: %v\n", err)
+		return
 	}
 
 	log.Printf("Tailwind is watching with PID %d", tailWindCmd.Process.Pid)
 
-	// Optionally wait for the process to exit and log its exit
+	exited := make(chan struct{})
+	command.tailwindDone = exited
 	go func() {
+		defer close(exited)
 		err := tailWindCmd.Wait()
-		if err != nil {
-			fmt.Printf("Tailwind process exited with error: %v", err)
-		} else {
+		switch {
+		case ctx.Err() != nil:
+			log.Println("Tailwind stopped.")
+		case err != nil:
+			fmt.Printf("Tailwind process exited with error: %v\n", err)
+		default:
 			log.Println("Tailwind process exited normally.")
 		}
 	}()
+
+	output := command.tailwindOutput()
+	if output == "" {
+		return
+	}
+	timeout := time.After(tailwindReadyTimeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if info, err := os.Stat(output); err == nil && !info.ModTime().Before(started) {
+				log.Printf("Tailwind wrote %s", output)
+				return
+			}
+		case <-exited:
+			return
+		case <-timeout:
+			log.Printf("Tailwind did not write %s after %s, starting anyway", output, tailwindReadyTimeout)
+			return
+		}
+	}
+}
+
+// tailwindOutput is the file given to Tailwind with -o or --output.
+func (command *HotReloadCommand) tailwindOutput() string {
+	for i, arg := range command.tailwindArgs {
+		switch {
+		case (arg == "-o" || arg == "--output") && i+1 < len(command.tailwindArgs):
+			return command.tailwindArgs[i+1]
+		case strings.HasPrefix(arg, "--output="):
+			return strings.TrimPrefix(arg, "--output=")
+		}
+	}
+	return ""
+}
+
+// runHooks runs shell commands from the "devServer" section, stopping at the
+// first failing one.
+func (command *HotReloadCommand) runHooks(ctx context.Context, hooks []string, output io.Writer) error {
+	for _, hook := range hooks {
+		log.Printf("Running %s", hook)
+		var hookCmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			hookCmd = exec.CommandContext(ctx, "cmd", "/C", hook)
+		} else {
+			hookCmd = exec.CommandContext(ctx, "sh", "-c", hook)
+		}
+		hookCmd.Stdout = os.Stdout
+		hookCmd.Stderr = io.MultiWriter(os.Stderr, output)
+		setProcessGroup(hookCmd)
+		hookCmd.Cancel = func() error { return terminateProcessTree(hookCmd) }
+		if err := hookCmd.Run(); err != nil {
+			return fmt.Errorf("%s: %w", hook, err)
+		}
+	}
+	return nil
 }
 
-func (command *HotReloadCommand) rebuild() {
+// watchPublicChanges tells the browser to swap stylesheets written to public/,
+// like the Tailwind output, without reloading the page.
+func (command *HotReloadCommand) watchPublicChanges(ctx context.Context) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Printf("error creating public watcher: %v", err)
+		return
+	}
+	defer watcher.Close()
+	err = filepath.Walk("public", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("error walking through public directory: %v", err)
+		return
+	}
+
+	// Tailwind writes its output in several steps, wait for the last one
+	changed := map[string]bool{}
+	flush := time.NewTimer(time.Hour)
+	flush.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Ext(event.Name) != ".css" || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			changed["/"+filepath.ToSlash(event.Name)] = true
+			flush.Reset(cssChangeDelay)
+		case <-flush.C:
+			for path := range changed {
+				log.Printf("Stylesheet changed: %s", path)
+				command.cli.Proxy.Sse.Send("css", path)
+			}
+			clear(changed)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Println("Public watcher error:", err)
+		}
+	}
+}
+
+// scheduleRebuild rebuilds the app for the changed files, aborting the build
+// in flight: its files are rebuilt with these ones.
+func (command *HotReloadCommand) scheduleRebuild(changed []string) {
+	command.queueMutex.Lock()
+	if command.buildCancel != nil {
+		command.buildCancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	command.buildCancel = cancel
+	command.queuedChanges = append(command.queuedChanges, changed...)
+	if changed == nil {
+		command.queuedFullReload = true
+	}
+	command.queueMutex.Unlock()
+
+	command.rebuild(ctx)
+}
+
+// takeQueuedChanges returns the files to rebuild, or nil when the browser
+// must reload fully.
+func (command *HotReloadCommand) takeQueuedChanges() []string {
+	command.queueMutex.Lock()
+	defer command.queueMutex.Unlock()
+	changed := command.queuedChanges
+	if command.queuedFullReload {
+		changed = nil
+	}
+	command.queuedChanges = nil
+	command.queuedFullReload = false
+	return changed
+}
+
+// requeueChanges hands the files of an aborted or failed build to the next
+// one.
+func (command *HotReloadCommand) requeueChanges(changed []string) {
+	command.queueMutex.Lock()
+	defer command.queueMutex.Unlock()
+	command.queuedChanges = append(command.queuedChanges, changed...)
+	if changed == nil {
+		command.queuedFullReload = true
+	}
+}
+
+// rebuild regenerates and restarts the app with the queued changes. It gives
+// up as soon as ctx is canceled by a newer change.
+func (command *HotReloadCommand) rebuild(ctx context.Context) {
 	command.mutex.Lock()
 	defer command.mutex.Unlock()
+	// A newer change arrived while waiting, its rebuild takes over
+	if ctx.Err() != nil || command.stopped {
+		return
+	}
+	changed := command.takeQueuedChanges()
+
+	var hookOutput bytes.Buffer
+	if err := command.runHooks(ctx, command.preRebuild, &hookOutput); err != nil {
+		command.requeueChanges(changed)
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Printf("error running pre rebuild command %v\n", err)
+		command.cli.Proxy.SendBuildError(proxy.BuildError{Stage: "preRebuild", Problems: []proxy.BuildProblem{}, Output: err.Error() + "\n" + hookOutput.String()})
+		return
+	}
 
 	log.Println("Build routes...")
 	if err := command.cli.FileBasedRouter.Render(command.cli.GetConfig().GoModName); err != nil {
 		fmt.Printf("error building routes: %v", err)
+		command.cli.Proxy.SendBuildError(proxy.BuildError{Stage: "routes", Problems: []proxy.BuildProblem{}, Output: err.Error()})
+		// The fix must reload what changed before it too
+		command.requeueChanges(changed)
 		return
 	}
 
 	log.Println("Build templ...")
-	if err := command.cli.Templ.Render(); err != nil {
+	var templOutput bytes.Buffer
+	if err := command.cli.Templ.Generate(io.MultiWriter(os.Stdout, &templOutput)); err != nil {
 		fmt.Printf("error building templ: %v", err)
+		command.cli.Proxy.SendBuildError(proxy.ParseBuildOutput("templ", templOutput.String()))
+		command.requeueChanges(changed)
+		return
+	}
+
+	if ctx.Err() != nil {
+		command.requeueChanges(changed)
 		return
 	}
 
 	log.Println("Build app...")
-	buildCmd := exec.Command("go", "build", "-o", command.mainBinaryName, "main.go")
+	var buildOutput bytes.Buffer
+	buildArgs := []string{"build", "-o", command.mainBinaryName}
+	if command.debugger != nil {
+		buildArgs = append(buildArgs, debugBuildFlags)
+	}
+	buildCmd := exec.CommandContext(ctx, "go", append(buildArgs, "main.go")...)
 	buildCmd.Stdout = os.Stdout
-	buildCmd.Stderr = os.Stderr
+	buildCmd.Stderr = io.MultiWriter(os.Stderr, &buildOutput)
+	// go build runs the compiler in child processes, stop them too
+	setProcessGroup(buildCmd)
+	buildCmd.Cancel = func() error { return terminateProcessTree(buildCmd) }
 	if err := buildCmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			log.Println("Build canceled by newer changes")
+			command.requeueChanges(changed)
+			return
+		}
 		fmt.Printf("error building app: %v", err)
+		command.cli.Proxy.SendBuildError(proxy.ParseBuildOutput("go build", buildOutput.String()))
+		command.requeueChanges(changed)
 		return
 	}
 
@@ -247,24 +754,287 @@ func (command *HotReloadCommand) rebuild() {
 		log.Println("Stopping previous go run process...")
 		command.runCancel()
 		command.runCancel = nil
+		// Wait for the graceful shutdown so the new process can bind the port
+		<-command.runDone
 	}
 	log.Println("Running app...")
-	ctx, cancel := context.WithCancel(context.Background())
+	runCtx, cancel := context.WithCancel(context.Background())
 	command.runCancel = cancel
+	runDone := make(chan struct{})
+	command.runDone = runDone
+	started := make(chan error, 1)
+	go func() {
+		defer close(runDone)
+		command.superviseApp(runCtx, started)
+	}()
+
+	select {
+	case <-ctx.Done():
+		command.requeueChanges(changed)
+		return
+	case err := <-started:
+		if err != nil {
+			// The supervisor shows the crash and keeps restarting the app
+			command.requeueChanges(changed)
+			fmt.Printf("error starting app: %v\n", err)
+			return
+		}
+	}
+	command.cli.Proxy.ClearBuildError()
+	command.notifyBrowser(changed)
 
+	if err := command.runHooks(ctx, command.postRebuild, io.Discard); err != nil && ctx.Err() == nil {
+		fmt.Printf("error running post rebuild command %v\n", err)
+	}
+}
+
+// superviseApp runs the built app until ctx is canceled, restarting it with a
+// growing delay whenever it exits on its own. started receives the outcome of
+// the first start, later restarts reload the browser themselves.
+func (command *HotReloadCommand) superviseApp(ctx context.Context, started chan<- error) {
+	delay := restartMinDelay
+	for attempt := 0; ; attempt++ {
+		output := newTailBuffer(crashOutputSize)
+		runCmd := command.appCommand(ctx, output)
+		launched := time.Now()
+		exited := make(chan struct{})
+		var runErr error
+		if runErr = runCmd.Start(); runErr == nil {
+			go func() {
+				defer close(exited)
+				runErr = runCmd.Wait()
+			}()
+			if command.debugger != nil {
+				go command.debugger.attach(ctx, runCmd, exited)
+			}
+		} else {
+			close(exited)
+		}
+
+		readyErr := command.waitUntilReady(ctx, exited)
+		if attempt == 0 {
+			started <- readyErr
+		} else if readyErr == nil {
+			log.Println("App restarted")
+			command.cli.Proxy.ClearBuildError()
+			command.cli.Proxy.Sse.Send("message", "reload")
+		}
+
+		<-exited
+		if ctx.Err() != nil {
+			return
+		}
+		if command.debugger != nil {
+			runErr = command.debugger.exitError(runErr)
+		}
+		stage := "runtime"
+		if readyErr != nil {
+			stage = "startup"
+		}
+		if time.Since(launched) > stableRunTime {
+			delay = restartMinDelay
+		}
+		fmt.Printf("App crashed (%v), restarting in %s\n", runErr, delay)
+		command.cli.Proxy.SendBuildError(proxy.ParseBuildOutput(stage, output.String()))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, restartMaxDelay)
+	}
+}
+
+// appCommand runs the built app, copying its errors to output.
+func (command *HotReloadCommand) appCommand(ctx context.Context, output io.Writer) *exec.Cmd {
+	if command.debugger != nil {
+		return command.debugger.command(ctx, command.mainBinaryName, output)
+	}
 	runCmd := exec.CommandContext(ctx, command.mainBinaryName)
 	runCmd.Stdout = os.Stdout
-	runCmd.Stderr = os.Stderr
-	command.runCmd = runCmd
-	command.cli.Proxy.Sse.Send("message", "reload")
-	go func() {
-		if err := runCmd.Run(); err != nil {
-			if ctx.Err() == nil {
-				fmt.Printf("error running app: %v", err)
+	runCmd.Stderr = io.MultiWriter(os.Stderr, output)
+	setProcessGroup(runCmd)
+	// Ask the app to shut down like the Lambda web adapter does, killing it only
+	// if it is still running after the wait delay
+	runCmd.Cancel = func() error { return terminateProcessTree(runCmd) }
+	runCmd.WaitDelay = 15 * time.Second
+	return runCmd
+}
+
+// waitUntilReady polls the restarted app until it answers. It fails when the
+// app exits first, and gives up waiting after the ready timeout.
+func (command *HotReloadCommand) waitUntilReady(ctx context.Context, exited <-chan struct{}) error {
+	timeout := time.After(command.readyTimeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-exited:
+			return errors.New("app exited before it was ready")
+		case <-timeout:
+			log.Printf("App not ready after %s, reloading anyway", command.readyTimeout)
+			return nil
+		case <-ticker.C:
+			if command.isReady(ctx) {
+				return nil
 			}
 		}
-	}()
+	}
+}
+
+func (command *HotReloadCommand) isReady(ctx context.Context) bool {
+	if command.readyPath == "" {
+		conn, err := net.DialTimeout("tcp", command.appURL.Host, time.Second)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, command.appURL.JoinPath(command.readyPath).String(), nil)
+	if err != nil {
+		return false
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return false
+	}
+	response.Body.Close()
+	return response.StatusCode < http.StatusInternalServerError
+}
+
+// notifyBrowser morphs the routes rendered by the changed templ files into the
+// page. Anything else, like Go code or a layout, needs a full reload.
+func (command *HotReloadCommand) notifyBrowser(changed []string) {
+	if len(changed) == 0 || slices.ContainsFunc(changed, command.needsFullReload) {
+		command.cli.Proxy.Sse.Send("message", "reload")
+		return
+	}
+	command.cli.Proxy.SendFragments(command.cli.FileBasedRouter.RoutesForTemplFiles(changed))
+}
+
+func (command *HotReloadCommand) needsFullReload(path string) bool {
+	if filepath.Ext(path) != ".templ" {
+		return true
+	}
+	path = filepath.Clean(path)
+	for _, dir := range command.layoutDirs {
+		if strings.HasPrefix(path, dir+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// exposed reports whether the proxy accepts connections from other devices.
+func (command *HotReloadCommand) exposed() bool {
+	if command.host == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(command.host)
+	return ip == nil || !ip.IsLoopback()
+}
+
+// localAddress is the address the browser of this machine opens, the proxy
+// does not listen on loopback when bound to a single network address.
+func (command *HotReloadCommand) localAddress() string {
+	ip := net.ParseIP(command.host)
+	if ip != nil && !ip.IsLoopback() && !ip.IsUnspecified() {
+		return command.host
+	}
+	return "127.0.0.1"
+}
+
+func (command *HotReloadCommand) proxyURL(scheme, address string) string {
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(address, strconv.Itoa(command.proxyPort)))
+}
+
+// networkAddresses lists the addresses other devices reach the proxy on: the
+// IPv4 addresses of every network interface when it binds all of them.
+func (command *HotReloadCommand) networkAddresses() []string {
+	if !command.exposed() {
+		return nil
+	}
+	if ip := net.ParseIP(command.host); ip == nil || !ip.IsUnspecified() {
+		return []string{command.host}
+	}
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		log.Printf("error listing network interfaces: %v", err)
+		return nil
+	}
+	var addresses []string
+	for _, networkInterface := range interfaces {
+		if networkInterface.Flags&net.FlagUp == 0 || networkInterface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		interfaceAddresses, err := networkInterface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, address := range interfaceAddresses {
+			ipNet, ok := address.(*net.IPNet)
+			if !ok || ipNet.IP.To4() == nil || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			addresses = append(addresses, ipNet.IP.String())
+		}
+	}
+	// Home and office networks use private addresses, list them first
+	sort.SliceStable(addresses, func(i, j int) bool {
+		return net.ParseIP(addresses[i]).IsPrivate() && !net.ParseIP(addresses[j]).IsPrivate()
+	})
+	return addresses
+}
 
+// printNetworkURLs shows where other devices open the app, with a QR code of
+// the first address for phones.
+func (command *HotReloadCommand) printNetworkURLs(scheme string, addresses []string) {
+	if len(addresses) == 0 {
+		fmt.Println("📱 No network address found, check that this machine is connected")
+		return
+	}
+	for _, address := range addresses {
+		fmt.Printf("📱 On your network: %s\n", command.proxyURL(scheme, address))
+	}
+	qr, err := qrcode.New(command.proxyURL(scheme, addresses[0]), qrcode.Medium)
+	if err != nil {
+		log.Printf("error creating QR code: %v", err)
+		return
+	}
+	fmt.Print(qr.ToSmallString(false))
+	if command.https {
+		fmt.Println("   Devices must trust the local CA too, open its file on them to install it.")
+	}
+}
+
+// printTrustInstructions explains how to trust the local CA, once it is
+// trusted the browser accepts every certificate hot reload signs with it.
+func (command *HotReloadCommand) printTrustInstructions() {
+	caFile, err := filepath.Abs(command.certificate.CAFile)
+	if err != nil {
+		caFile = command.certificate.CAFile
+	}
+	if !command.certificate.NewCA {
+		fmt.Printf("🔒 HTTPS certificate signed by the local CA %s\n", caFile)
+		return
+	}
+	fmt.Printf("🔒 Created a local CA for HTTPS, trust it once so browsers accept the proxy certificate:\n")
+	switch runtime.GOOS {
+	case "windows":
+		fmt.Printf("   certutil -addstore -user Root \"%s\"\n", caFile)
+	case "darwin":
+		fmt.Printf("   sudo security add-trusted-cert -d -r trustRoot -k /Library/Keychains/System.keychain \"%s\"\n", caFile)
+	default:
+		fmt.Printf("   sudo cp \"%s\" /usr/local/share/ca-certificates/gothic-local-ca.crt && sudo update-ca-certificates\n", caFile)
+		fmt.Printf("   Chrome and Firefox keep their own store: certutil -d sql:$HOME/.pki/nssdb -A -t C,, -n \"Gothic local CA\" -i \"%s\"\n", caFile)
+	}
+	fmt.Printf("   Never share %s, it can sign certificates for any site.\n", command.certificate.CAKeyFile)
 }
 
 func (command *HotReloadCommand) openBrowser(url string) error {
@@ -283,3 +1053,30 @@ func (command *HotReloadCommand) openBrowser(url string) error {
 
 	return cmd.Start()
 }
+
+// tailBuffer keeps the last bytes written to it.
+type tailBuffer struct {
+	mutex sync.Mutex
+	size  int
+	data  []byte
+}
+
+func newTailBuffer(size int) *tailBuffer {
+	return &tailBuffer{size: size}
+}
+
+func (buffer *tailBuffer) Write(p []byte) (int, error) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	buffer.data = append(buffer.data, p...)
+	if len(buffer.data) > buffer.size {
+		buffer.data = buffer.data[len(buffer.data)-buffer.size:]
+	}
+	return len(p), nil
+}
+
+func (buffer *tailBuffer) String() string {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	return string(buffer.data)
+}
@@ -4,6 +4,8 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io/fs"
 	"os"
@@ -171,6 +173,13 @@ func (command *InitCommand) createHiddenFiles() error {
 	lowerId := strings.ToLower(upperId)
 	id := re.ReplaceAllString(lowerId, "-")
 
+	// Signs and encrypts session cookies, see the "sessions" section of main.go
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("error generating session secret: %v", err)
+	}
+	sessionSecret := hex.EncodeToString(secret)
+
 	var wg sync.WaitGroup
 	wg.Add(3)
 
@@ -180,7 +189,7 @@ func (command *InitCommand) createHiddenFiles() error {
 	}()
 
 	go func() {
-		os.WriteFile(".env", []byte(command.gothicCliData.Env), 0644)
+		os.WriteFile(".env", []byte(command.gothicCliData.Env+"\n"+`SESSION_SECRET: "`+sessionSecret+`"`), 0644)
 		wg.Done()
 	}()
 
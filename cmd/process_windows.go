@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup starts cmd in its own process group, so terminals don't
+// signal it directly and terminateProcessTree reaches its children too.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// terminateProcessTree stops cmd and every process it started. Windows has
+// no SIGTERM, the tree is killed right away.
+func terminateProcessTree(cmd *exec.Cmd) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
@@ -10,8 +10,12 @@ import (
 	"runtime"
 
 	helpers "github.com/felipegenef/gothicframework/pkg/helpers"
+	"github.com/felipegenef/gothicframework/pkg/helpers/compression"
+	"github.com/felipegenef/gothicframework/pkg/helpers/cors"
 	proxy "github.com/felipegenef/gothicframework/pkg/helpers/proxy"
 	routes "github.com/felipegenef/gothicframework/pkg/helpers/routes"
+	"github.com/felipegenef/gothicframework/pkg/helpers/securityheaders"
+	"github.com/felipegenef/gothicframework/pkg/helpers/sessions"
 )
 
 type GothicCli struct {
@@ -75,7 +79,14 @@ func (cli *GothicCli) GetConfig() Config {
 	if cli.config != nil {
 		return *cli.config
 	}
-	var config Config
+	// Sections missing from the file keep their default values
+	config := Config{
+		Compression:     compression.DefaultConfig,
+		SecurityHeaders: securityheaders.DefaultConfig,
+		CORS:            cors.DefaultConfig,
+		Sessions:        sessions.DefaultConfig,
+		DevServer:       DefaultDevServerConfig,
+	}
 	file, err := os.Open("gothic-config.json")
 	if err != nil {
 		log.Fatalf("Error opening file: %v", err)
@@ -1,12 +1,75 @@
 package cli
 
+import (
+	"github.com/felipegenef/gothicframework/pkg/helpers/app"
+	"github.com/felipegenef/gothicframework/pkg/helpers/compression"
+	"github.com/felipegenef/gothicframework/pkg/helpers/cors"
+	"github.com/felipegenef/gothicframework/pkg/helpers/securityheaders"
+	"github.com/felipegenef/gothicframework/pkg/helpers/sessions"
+)
+
 type Config struct {
 	ProjectName    string `json:"projectName"`
 	GoModName      string `json:"goModuleName"`
 	OptimizeImages struct {
 		LowResolutionRate int `json:"lowResolutionRate"`
 	} `json:"optimizeImages"`
-	Deploy *DeployConfig `json:"deploy"`
+	Compression     compression.Config     `json:"compression"`
+	SecurityHeaders securityheaders.Config `json:"securityHeaders"`
+	CORS            cors.Config            `json:"cors"`
+	Sessions        sessions.Config        `json:"sessions"`
+	DevServer       DevServerConfig        `json:"devServer"`
+	Deploy          *DeployConfig          `json:"deploy"`
+}
+
+// DevServerConfig is the "devServer" section of gothic-config.json, used by
+// the hot-reload command. Its flags override each field.
+type DevServerConfig struct {
+	// Host is the address the proxy binds to, "0.0.0.0" opens it to the
+	// other devices of the network.
+	Host      string `json:"host"`
+	ProxyPort int    `json:"proxyPort"`
+	// AppPort is the port of the app behind the proxy. Zero keeps the
+	// HTTP_LISTEN_ADDR of the .env file, or 8080.
+	AppPort           int      `json:"appPort"`
+	WatchDirs         []string `json:"watchDirs"`
+	ExcludedDirs      []string `json:"excludedDirs"`
+	LayoutDirs        []string `json:"layoutDirs"`
+	WatchedExtensions []string `json:"watchedExtensions"`
+	// Binary is where the app is built, ".exe" is added on Windows.
+	Binary string `json:"binary"`
+	// TailwindArgs are passed to Tailwind next to --watch=always.
+	TailwindArgs []string `json:"tailwindArgs"`
+	// ReadyPath is polled on the app after each restart, the browser reloads
+	// once it answers below 500. Empty only waits for the port to open.
+	ReadyPath         string `json:"readyPath"`
+	ReadyTimeoutInSec int    `json:"readyTimeoutInSec"`
+	// PreRebuild commands run in the shell before each build, a failing one
+	// stops it. PostRebuild commands run once the new app started.
+	PreRebuild  []string `json:"preRebuild"`
+	PostRebuild []string `json:"postRebuild"`
+	// DebugPort is where Delve listens with --debug.
+	DebugPort int `json:"debugPort"`
+	// HTTPS serves the proxy with a certificate signed by a local CA, cached
+	// in .gothicCli/certs.
+	HTTPS bool `json:"https"`
+}
+
+var DefaultDevServerConfig = DevServerConfig{
+	Host:              "localhost",
+	ProxyPort:         3000,
+	WatchDirs:         []string{"src"},
+	ExcludedDirs:      []string{"assets", "tmp", "vendor", "public", "routes"},
+	LayoutDirs:        []string{"src/layouts"},
+	WatchedExtensions: []string{".go", ".tpl", ".tmpl", ".templ", ".html"},
+	Binary:            "tmp/main",
+	TailwindArgs:      []string{"-i", "src/css/app.css", "-o", "public/styles.css", "--minify"},
+	ReadyPath:         app.ReadyPath,
+	ReadyTimeoutInSec: 30,
+	PreRebuild:        []string{},
+	PostRebuild:       []string{},
+	DebugPort:         2345,
+	HTTPS:             false,
 }
 
 type DeployConfig struct {
@@ -24,4 +87,10 @@ type EnvVariables struct {
 	CustomDomain   *string                `json:"customDomain"`
 	CertificateArn *string                `json:"certificateArn"`
 	ENV            map[string]interface{} `json:"env,omitempty"`
+	// SessionSecretParameter names the SSM SecureString holding the
+	// SESSION_SECRET of the stage, read on deploy. Secrets Manager secrets are
+	// named "/aws/reference/secretsmanager/<secret>". When empty, deploy uses
+	// the SESSION_SECRET of its own environment. Never put it in ENV, which
+	// is kept in plain text.
+	SessionSecretParameter *string `json:"sessionSecretParameter"`
 }
@@ -6,6 +6,7 @@ package routes
 *
 */
 import (
+	"errors"
 	{{- if .ImportDefault }}
 	routes "github.com/felipegenef/gothicframework/pkg/helpers/routes"
 	{{- end }}
@@ -17,12 +18,14 @@ import (
 )
 
 
-func RegisterFileBasedRoutes(r chi.Router) {
+func RegisterFileBasedRoutes(r chi.Router) error {
+	var errs []error
 	{{ range .Routes }}
 		{{.ConfigPackageName}}.{{.ConfigName}}.RegisterRoute(r,"{{.HttpPath}}",{{.PackageName}}.{{.FunctionName}})
 	{{ end }}
 	{{ range .ApiRoutes }}
-		{{.ConfigPackageName}}.{{.ConfigName}}.RegisterRoute(r,"{{.HttpPath}}",{{.PackageName}}.{{.FunctionName}})
+		errs = append(errs, {{.ConfigPackageName}}.{{.ConfigName}}.RegisterRoute(r,"{{.HttpPath}}",{{.PackageName}}.{{.FunctionName}}))
+		{{.ConfigPackageName}}.{{.ConfigName}}.RegisterPreflight(r,"{{.HttpPath}}")
 	{{ end }}
-
+	return errors.Join(errs...)
 }
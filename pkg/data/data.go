@@ -49,6 +49,8 @@ node_modules
 tmp
 optimize/*
 public/styles.css
+.gothicCli/certs
+.gothicCli/runtime-config.json
 template.yaml
 samconfig.toml
 Dockerfile`
@@ -100,6 +102,8 @@ var DefaultCLIData = GothicCliData{
 		// page files
 		"src/pages/index.templ":      srcFolder,
 		"src/pages/revalidate.templ": srcFolder,
+		"src/pages/login.templ":      srcFolder,
+		"src/pages/account.templ":    srcFolder,
 		// layout files
 		"src/layouts/layout.templ": srcFolder,
 		// css files
@@ -109,6 +113,8 @@ var DefaultCLIData = GothicCliData{
 		"src/components/lazyLoad.templ":   srcFolder,
 		// api files
 		"src/api/helloWorld.go": srcFolder,
+		"src/api/login.go":      srcFolder,
+		"src/api/logout.go":     srcFolder,
 		// root files
 		"makefile":           makeFile,
 		"tailwind.config.js": tailwindConfig,
@@ -150,6 +156,8 @@ var DefaultCLIData = GothicCliData{
 	CustomTemplateBasedPages: map[string]string{
 		"src/pages/revalidate.templ": "Revalidate",
 		"src/pages/index.templ":      "Index",
+		"src/pages/login.templ":      "Login",
+		"src/pages/account.templ":    "Account",
 	},
 	CustomTemplateBasedComponents: map[string]string{
 		"src/components/helloWorld.templ": "HelloWorld",
@@ -157,5 +165,7 @@ var DefaultCLIData = GothicCliData{
 	},
 	CustomTemplateBasedRoutes: map[string]string{
 		"src/api/helloWorld.go": "HelloWorld",
+		"src/api/login.go":      "Login",
+		"src/api/logout.go":     "Logout",
 	},
 }
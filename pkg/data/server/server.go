@@ -1,6 +1,8 @@
 {{.MainServerPackageName}}
 
 import (
+	"context"
+	_ "embed"
 	"log"
 	"log/slog"
 	"net/http"
@@ -8,18 +10,147 @@ import (
 
 	"{{.GoModName}}/src/routes"
 	"github.com/felipegenef/gothicframework/components"
+	"github.com/felipegenef/gothicframework/pkg/helpers/app"
+	"github.com/felipegenef/gothicframework/pkg/helpers/compression"
+	"github.com/felipegenef/gothicframework/pkg/helpers/cors"
+	"github.com/felipegenef/gothicframework/pkg/helpers/csp"
+	"github.com/felipegenef/gothicframework/pkg/helpers/csrf"
+	"github.com/felipegenef/gothicframework/pkg/helpers/metrics"
+	"github.com/felipegenef/gothicframework/pkg/helpers/ratelimit"
+	"github.com/felipegenef/gothicframework/pkg/helpers/securityheaders"
+	"github.com/felipegenef/gothicframework/pkg/helpers/sessions"
+	"github.com/felipegenef/gothicframework/pkg/helpers/telemetry"
 
-	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/joho/godotenv"
 )
 
+//go:embed gothic-config.json
+var gothicConfig []byte
+
 func {{.MainServerFunctionName}} {
 	godotenv.Load()
 	var localServe = os.Getenv("LOCAL_SERVE")
 	var isLocal = len(localServe) > 0 && localServe == "true"
 	router := chi.NewMux()
-	router.Use(middleware.Logger)
+
+	/**
+	*                              Logs and tracing
+	*
+	* Every request is logged as a JSON line with its request ID, route pattern, status
+	* and duration. Incoming X-Request-Id headers are reused so logs can be correlated
+	* across services. Use slog anywhere in your code and the logs keep the same format.
+	*
+	* Set "enabled" in the "telemetry" section of gothic-config.json to export OpenTelemetry
+	* spans to an OTLP/HTTP collector. Spans cover the whole request, route Middlewares,
+	* local cache hits and misses and templ rendering.
+	*
+	 */
+	telemetryConfig, err := telemetry.LoadConfig(gothicConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger := telemetry.NewLogger(telemetryConfig)
+	slog.SetDefault(logger)
+	shutdownTelemetry, err := telemetry.Setup(context.Background(), telemetryConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	router.Use(telemetry.RequestLogger(logger))
+
+	/**
+	*                              Prometheus metrics
+	*
+	* Request counts, latencies, templ render durations and local cache hits, misses and
+	* sizes are exposed on the path set in the "metrics" section of gothic-config.json
+	* (default /metrics). Every series is labelled by route pattern, like "/blog/{slug}".
+	* They are always served locally, but only once deployed when "enabled" is set: the
+	* endpoint is then public, so protect it first. Register your own collectors on
+	* metrics.Registry.
+	*
+	 */
+	metricsConfig, err := metrics.LoadConfig(gothicConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if isLocal {
+		metricsConfig.Enabled = true
+	}
+	if metricsConfig.Enabled {
+		router.Use(metrics.Middleware)
+	}
+
+	/**
+	*                              Response compression
+	*
+	* HTML, JSON, CSS and JS responses are compressed with brotli or gzip depending on
+	* what the browser accepts. Change the "compression" section of gothic-config.json
+	* to pick the algorithms, levels and content types, or to disable it.
+	*
+	 */
+	compressionConfig, err := compression.LoadConfig(gothicConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	router.Use(compression.New(compressionConfig))
+
+	/**
+	*                              Security headers
+	*
+	* HSTS, X-Content-Type-Options, X-Frame-Options, Referrer-Policy and Permissions-Policy
+	* are set from the "securityHeaders" section of gothic-config.json. Deploying applies
+	* the same values to a CloudFront response headers policy, so pages and public assets
+	* cached at the edge carry them too. HSTS is skipped locally, browsers would otherwise
+	* force HTTPS on localhost.
+	*
+	 */
+	securityHeadersConfig, err := securityheaders.LoadConfig(gothicConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if isLocal {
+		securityHeadersConfig.HSTS.Enabled = false
+	}
+	router.Use(securityheaders.New(securityHeadersConfig))
+
+	/**
+	*                              Content-Security-Policy
+	*
+	* Every request gets a random nonce, sent in the Content-Security-Policy header and
+	* available to templates through templ.GetNonce(ctx). On DYNAMIC pages only <script>
+	* tags carrying it run, which stops injected scripts. STATIC and ISR pages are shared
+	* through CloudFront and can't carry a nonce, they get "sharedPolicy" instead, which
+	* only runs same-origin scripts and the URLs or hashes it lists. Edit "policy" and
+	* "sharedPolicy" in the "csp" section of gothic-config.json to allow other origins
+	* ({nonce} is replaced on every request), or set "reportOnly" to true to try a policy
+	* without blocking anything.
+	*
+	 */
+	cspConfig, err := csp.LoadConfig(gothicConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	router.Use(csp.New(cspConfig))
+
+	/**
+	*                              CSRF protection
+	*
+	* POST, PUT, PATCH and DELETE requests must carry the token rendered by the layout
+	* hx-headers (for HTMX) or by @csrf.Field() (for regular forms), otherwise they get a
+	* 403. Requests to src/api routes sending an "Authorization: Bearer" header are exempt,
+	* change "bearerAuthPaths" and "exemptPaths" in the "csrf" section of gothic-config.json
+	* to adjust it. Tokens are per visitor, so STATIC and ISR pages get theirs from "tokenPath"
+	* through @csrf.Bootstrap() in the layout.
+	*
+	 */
+	csrfConfig, err := csrf.LoadConfig(gothicConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if isLocal {
+		csrfConfig.Secure = false
+	}
+	router.Use(csrf.New(csrfConfig))
 
 	/**
 	*                              Public assets folder
@@ -38,7 +169,83 @@ func {{.MainServerFunctionName}} {
 		router.Handle("/public/*", http.StripPrefix("/public/", http.FileServer(http.Dir("./public/"))))
 	}
 
-	router.Group(routes.RegisterFileBasedRoutes)
+	/**
+	*                              Sessions
+	*
+	* Sessions live in a cookie encrypted with the SESSION_SECRET environment variable,
+	* generated in your .env file. Never put it in gothic-config.json: deploy reads it from
+	* the SESSION_SECRET of your shell, or from the SSM parameter named in the
+	* "sessionSecretParameter" of the stage, and passes it to the Lambda as a NoEcho
+	* parameter. Set "store" in the "sessions" section to "memory" or "file" to keep them on
+	* the server, or pass your own store, like sessions.NewSQLStore, to
+	* sessions.NewWithStore. Wrap a page Middleware with sessions.RequireLogin to protect it,
+	* like the example in src/pages/account.templ. Set "enabled" to false if you don't use
+	* sessions, deploy then doesn't need the secret.
+	*
+	 */
+	sessionConfig, err := sessions.LoadConfig(gothicConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Browsers drop Secure cookies sent over plain HTTP from LAN addresses
+	if isLocal {
+		sessionConfig.Secure = false
+	}
+	if sessionConfig.Enabled {
+		sessionManager, err := sessions.New(sessionConfig, os.Getenv("SESSION_SECRET"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		sessions.SetDefault(sessionManager)
+	}
+
+	/**
+	*                              CORS
+	*
+	* Routes in src/api only answer other origins listed in "allowedOrigins" of the "cors"
+	* section (empty keeps CORS off). OPTIONS preflights are registered for you, and a route
+	* can use its own settings with the CORS field of its routes.ApiRouteConfig. Cookies are
+	* only sent cross origin with "allowCredentials", and such requests still need a CSRF
+	* token unless they use Bearer auth.
+	*
+	 */
+	corsConfig, err := cors.LoadConfig(gothicConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cors.SetDefault(corsConfig)
+
+	/**
+	*                              Rate limits
+	*
+	* Routes with a RateLimit count requests per client IP by default. The Lambda created by
+	* the deploy command only accepts requests from CloudFront, so it reads the IP CloudFront
+	* forwards. Leave "behindCloudFront" in the "rateLimit" section off when the server runs
+	* anywhere else, clients could send the header themselves.
+	*
+	 */
+	rateLimitConfig, err := ratelimit.LoadConfig(gothicConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Nothing adds the CloudFront header locally
+	if isLocal {
+		rateLimitConfig.BehindCloudFront = false
+	}
+	ratelimit.SetDefault(rateLimitConfig)
+
+	if metricsConfig.Enabled {
+		router.Handle(metricsConfig.Path, metrics.Handler())
+	}
+
+	// Fails on API routes with an invalid CORS config
+	var routesErr error
+	router.Group(func(r chi.Router) {
+		routesErr = routes.RegisterFileBasedRoutes(r)
+	})
+	if routesErr != nil {
+		log.Fatal(routesErr)
+	}
 	/**
 	*                            📸 OptimizedImage Component
 	*
@@ -58,7 +265,39 @@ func {{.MainServerFunctionName}} {
 	*/
 	gothicComponents.OptimizedImageConfig.RegisterRoute(router,"/optimizedImage/{name}/{extension}",gothicComponents.OptimizedImage)
 
-	port := os.Getenv("HTTP_LISTEN_ADDR")
-	slog.Info("application running", "port", port)
-	log.Fatal(http.ListenAndServe(port, router))
+	/**
+	*                              Server lifecycle
+	*
+	* The app listens on HTTP_LISTEN_ADDR with the timeouts from the "server" section of
+	* gothic-config.json. On SIGTERM (sent by the AWS Lambda web adapter and by hot reload)
+	* it stops accepting requests, waits for in-flight ones and then runs your shutdown hooks.
+	* Use OnStart and OnShutdown to open and close resources like database pools:
+	*
+	*   application.OnStart(func(ctx context.Context) error {
+	*       db, err = sql.Open("postgres", os.Getenv("DATABASE_URL"))
+	*       return err
+	*   })
+	*
+	* The app also serves /_gothicframework/health, /_gothicframework/ready and
+	* /_gothicframework/version for load balancers and on-call tooling. Readiness
+	* aggregates the checks you register:
+	*
+	*   application.AddReadinessCheck("database", func(ctx context.Context) error {
+	*       return db.PingContext(ctx)
+	*   })
+	*
+	 */
+	serverConfig, err := app.LoadConfig(gothicConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	application := app.New(router, serverConfig)
+	application.OnShutdown(shutdownTelemetry)
+	application.OnShutdown(func(ctx context.Context) error {
+		slog.Info("application stopped")
+		return nil
+	})
+	if err := application.Run(); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/felipegenef/gothicframework/pkg/helpers/ratelimit"
 	routes "github.com/felipegenef/gothicframework/pkg/helpers/routes"
 )
 
@@ -26,12 +27,19 @@ type HelloWorldResponse struct {
  * `HelloWorldConfig` registers this handler as an API route.
  *
  * - `HttpMethod`: Specifies that this endpoint handles HTTP GET requests.
+ * - `RateLimit`: Allows each client 60 requests per minute, with bursts of up to 60. Clients over
+ *   the limit get `429 Too Many Requests` and a `Retry-After` header. Requests are keyed by IP by
+ *   default; use `ratelimit.ByHeader("X-Api-Key")` or `ratelimit.BySession` as `Key` to change it.
+ *
+ * Other origins can call this route once listed in the "cors" section of gothic-config.json. To
+ * open only this route, set `CORS: &cors.Config{AllowedOrigins: []string{"https://example.com"}}`.
  *
  * Since this is a pure API route, you don't need to define things like `Type` or `Middleware`.
  * All logic is handled directly in the handler function (`HelloWorld`).
  */
 var HelloWorldConfig = routes.ApiRouteConfig{
 	HttpMethod: routes.GET,
+	RateLimit:  &ratelimit.Limit{Requests: 60, WindowInSec: 60},
 }
 
 /**
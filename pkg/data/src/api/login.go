@@ -0,0 +1,45 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/felipegenef/gothicframework/pkg/helpers/ratelimit"
+	routes "github.com/felipegenef/gothicframework/pkg/helpers/routes"
+	"github.com/felipegenef/gothicframework/pkg/helpers/sessions"
+)
+
+/**
+ * `LoginConfig` handles the form of the login page (`src/pages/login.templ`).
+ *
+ * Replace the credentials check with a lookup in your users table, comparing password
+ * hashes (e.g. with golang.org/x/crypto/bcrypt) instead of plain text.
+ *
+ * `RateLimit` slows down password guessing: each IP gets 5 attempts, refilled over a
+ * minute, and then receives `429 Too Many Requests` with a `Retry-After` header.
+ */
+var LoginConfig = routes.ApiRouteConfig{
+	HttpMethod: routes.POST,
+	RateLimit:  &ratelimit.Limit{Requests: 5, WindowInSec: 60},
+}
+
+/**
+ * `Login` starts a session for the user with `sessions.Login`, which also rotates the
+ * session ID, and sends them back to the page they came from.
+ */
+func Login(w http.ResponseWriter, r *http.Request) {
+	next := sessions.SafeRedirect(r.FormValue("next"), "/account")
+	username := r.FormValue("username")
+	if username != "gothic" || r.FormValue("password") != "gothic" {
+		http.Redirect(w, r, "/login?error=1&next="+url.QueryEscape(next), http.StatusSeeOther)
+		return
+	}
+
+	if err := sessions.Login(w, r, username); err != nil {
+		slog.ErrorContext(r.Context(), "error logging in", "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	routes "github.com/felipegenef/gothicframework/pkg/helpers/routes"
+	"github.com/felipegenef/gothicframework/pkg/helpers/sessions"
+)
+
+/**
+ * `LogoutConfig` only accepts POST, so a link or an image on another site can't log
+ * your users out.
+ */
+var LogoutConfig = routes.ApiRouteConfig{
+	HttpMethod: routes.POST,
+}
+
+/**
+ * `Logout` destroys the session and expires its cookie.
+ */
+func Logout(w http.ResponseWriter, r *http.Request) {
+	if err := sessions.Logout(w, r); err != nil {
+		slog.ErrorContext(r.Context(), "error logging out", "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
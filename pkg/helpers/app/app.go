@@ -0,0 +1,144 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/felipegenef/gothicframework/pkg/helpers/config"
+)
+
+// Config is the "server" section of gothic-config.json.
+type Config struct {
+	ReadTimeoutInSec       int `json:"readTimeoutInSec"`
+	ReadHeaderTimeoutInSec int `json:"readHeaderTimeoutInSec"`
+	WriteTimeoutInSec      int `json:"writeTimeoutInSec"`
+	IdleTimeoutInSec       int `json:"idleTimeoutInSec"`
+	// ShutdownTimeoutInSec bounds how long in-flight requests and OnShutdown
+	// hooks may take once SIGTERM is received.
+	ShutdownTimeoutInSec int `json:"shutdownTimeoutInSec"`
+}
+
+var DefaultConfig = Config{
+	ReadTimeoutInSec:       15,
+	ReadHeaderTimeoutInSec: 5,
+	WriteTimeoutInSec:      30,
+	IdleTimeoutInSec:       60,
+	ShutdownTimeoutInSec:   10,
+}
+
+// Hook runs when the app starts or shuts down, e.g. to open or close a
+// database pool.
+type Hook func(ctx context.Context) error
+
+// App runs the Gothic server until SIGTERM or SIGINT is received, which is how
+// both the Lambda web adapter and the hot-reload command stop it.
+type App struct {
+	config     Config
+	handler    http.Handler
+	server     *http.Server
+	onStart    []Hook
+	onShutdown []Hook
+	checks     []namedCheck
+	ready      atomic.Bool
+}
+
+func New(handler http.Handler, config Config) *App {
+	return &App{
+		config:  config,
+		handler: handler,
+	}
+}
+
+// LoadConfig reads the "server" section of gothic-config.json, holding the
+// timeouts Run gives to the http.Server.
+func LoadConfig(gothicConfig []byte) (Config, error) {
+	return config.Section(gothicConfig, "server", DefaultConfig)
+}
+
+// OnStart registers a hook run before the server accepts requests. A failing
+// hook aborts Run.
+func (app *App) OnStart(hook Hook) {
+	app.onStart = append(app.onStart, hook)
+}
+
+// OnShutdown registers a hook run after in-flight requests have finished.
+// Hooks run in reverse registration order.
+func (app *App) OnShutdown(hook Hook) {
+	app.onShutdown = append(app.onShutdown, hook)
+}
+
+// Run starts the server on HTTP_LISTEN_ADDR and blocks until it is shut down.
+func (app *App) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, hook := range app.onStart {
+		if err := hook(ctx); err != nil {
+			return fmt.Errorf("error running start hook: %v", err)
+		}
+	}
+
+	port := os.Getenv("HTTP_LISTEN_ADDR")
+	if port == "" {
+		port = ":8080"
+	}
+	app.server = &http.Server{
+		Addr:              port,
+		Handler:           app.routes(),
+		ReadTimeout:       seconds(app.config.ReadTimeoutInSec),
+		ReadHeaderTimeout: seconds(app.config.ReadHeaderTimeoutInSec),
+		WriteTimeout:      seconds(app.config.WriteTimeoutInSec),
+		IdleTimeout:       seconds(app.config.IdleTimeoutInSec),
+	}
+
+	listener, err := net.Listen("tcp", port)
+	if err != nil {
+		return errors.Join(fmt.Errorf("error listening on %s: %v", port, err), app.shutdown())
+	}
+	app.ready.Store(true)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("application running", "port", port)
+		if err := app.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case err = <-serverErr:
+	case <-ctx.Done():
+		slog.Info("application shutting down")
+	}
+	return errors.Join(err, app.shutdown())
+}
+
+func (app *App) shutdown() error {
+	app.ready.Store(false)
+	ctx, cancel := context.WithTimeout(context.Background(), seconds(app.config.ShutdownTimeoutInSec))
+	defer cancel()
+
+	var errs []error
+	if err := app.server.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("error shutting down server: %v", err))
+	}
+	for i := len(app.onShutdown) - 1; i >= 0; i-- {
+		if err := app.onShutdown[i](ctx); err != nil {
+			errs = append(errs, fmt.Errorf("error running shutdown hook: %v", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func seconds(value int) time.Duration {
+	return time.Duration(value) * time.Second
+}
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HealthPath  = "/_gothicframework/health"
+	ReadyPath   = "/_gothicframework/ready"
+	VersionPath = "/_gothicframework/version"
+)
+
+// readinessTimeout bounds the time all readiness checks may take together.
+const readinessTimeout = 5 * time.Second
+
+// Check reports whether a dependency of the app, such as a database, is
+// available. A nil error means ready.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// BuildInfo identifies the running deployment.
+type BuildInfo struct {
+	AppID  string `json:"appId"`
+	Stage  string `json:"stage"`
+	GitSHA string `json:"gitSha"`
+}
+
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// AddReadinessCheck registers a check aggregated by the readiness endpoint.
+func (app *App) AddReadinessCheck(name string, check Check) {
+	app.checks = append(app.checks, namedCheck{name: name, check: check})
+}
+
+// routes serves the framework endpoints in front of the app handler, so they
+// skip user middlewares and stay cheap for load balancer probes.
+func (app *App) routes() http.Handler {
+	buildInfo := ReadBuildInfo()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+HealthPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET "+ReadyPath, app.serveReadiness)
+	mux.HandleFunc("GET "+VersionPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, buildInfo)
+	})
+	mux.Handle("/", app.handler)
+	return mux
+}
+
+// serveReadiness runs every check concurrently and answers 503 if one fails or
+// the app is starting or shutting down.
+func (app *App) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	response := readinessResponse{Status: "ready", Checks: map[string]string{}}
+	if !app.ready.Load() {
+		response.Status = "unavailable"
+		writeJSON(w, http.StatusServiceUnavailable, response)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range app.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := "ok"
+			if err := check.check(ctx); err != nil {
+				result = err.Error()
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			response.Checks[check.name] = result
+			if result != "ok" {
+				response.Status = "unavailable"
+			}
+		}()
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if response.Status != "ready" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, response)
+}
+
+// ReadBuildInfo collects the app ID, stage and git SHA. Deployments provide
+// them through the GOTHIC_APP_ID, GOTHIC_STAGE and GOTHIC_GIT_SHA environment
+// variables; locally they come from .gothicCli/app-id.txt and the VCS data Go
+// embeds in the binary.
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{
+		AppID:  os.Getenv("GOTHIC_APP_ID"),
+		Stage:  os.Getenv("GOTHIC_STAGE"),
+		GitSHA: os.Getenv("GOTHIC_GIT_SHA"),
+	}
+	if info.AppID == "" {
+		if content, err := os.ReadFile(".gothicCli/app-id.txt"); err == nil {
+			info.AppID = strings.TrimSpace(string(content))
+		}
+	}
+	if info.Stage == "" {
+		info.Stage = "local"
+	}
+	if info.GitSHA == "" {
+		if build, ok := debug.ReadBuildInfo(); ok {
+			modified := false
+			for _, setting := range build.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					info.GitSHA = setting.Value
+				case "vcs.modified":
+					modified = setting.Value == "true"
+				}
+			}
+			if modified && info.GitSHA != "" {
+				info.GitSHA += "-dirty"
+			}
+		}
+	}
+	return info
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
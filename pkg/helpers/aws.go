@@ -95,3 +95,16 @@ func (helper *AwsHelper) CleanCloudFrontCache(stackName string, stage string, re
 	fmt.Printf("Successfully reset CloudFront cache for distribution: %s\n", distributionId)
 	return nil
 }
+
+// GetSecureParameter returns the decrypted value of an SSM parameter. Secrets
+// Manager secrets are read through "/aws/reference/secretsmanager/<secret>".
+func (helper *AwsHelper) GetSecureParameter(name string, region string, awsProfile string) (string, error) {
+	getParameterCMD := exec.Command("aws", "ssm", "get-parameter", "--name", name, "--with-decryption", "--query", "Parameter.Value", "--output", "text", "--region", region, "--profile", awsProfile)
+	var out bytes.Buffer
+	getParameterCMD.Stdout = &out
+	getParameterCMD.Stderr = os.Stderr
+	if err := getParameterCMD.Run(); err != nil {
+		return "", fmt.Errorf("error reading SSM parameter %s: %v", name, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
@@ -0,0 +1,264 @@
+package compression
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/felipegenef/gothicframework/pkg/helpers/config"
+)
+
+// Config is the "compression" section of gothic-config.json.
+type Config struct {
+	Enabled bool `json:"enabled"`
+	// Algorithms lists the supported encodings by preference ("br", "gzip").
+	Algorithms   []string `json:"algorithms"`
+	GzipLevel    int      `json:"gzipLevel"`
+	BrotliLevel  int      `json:"brotliLevel"`
+	MinSize      int      `json:"minSize"`
+	ContentTypes []string `json:"contentTypes"`
+}
+
+var DefaultConfig = Config{
+	Enabled:     true,
+	Algorithms:  []string{"br", "gzip"},
+	GzipLevel:   gzip.DefaultCompression,
+	BrotliLevel: 5,
+	MinSize:     1024,
+	ContentTypes: []string{
+		"text/html",
+		"application/json",
+		"text/css",
+		"text/javascript",
+		"application/javascript",
+	},
+}
+
+// LoadConfig reads the "compression" section of gothic-config.json. Deploy
+// reads the same algorithms to pick the encodings CloudFront forwards, see
+// Accepts.
+func LoadConfig(gothicConfig []byte) (Config, error) {
+	return config.Section(gothicConfig, "compression", DefaultConfig)
+}
+
+// Accepts reports whether CloudFront should forward the given encoding to the
+// server, e.g. Accepts("gzip").
+func (config Config) Accepts(encoding string) bool {
+	if !config.Enabled {
+		return false
+	}
+	for _, algorithm := range config.Algorithms {
+		if algorithm == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+// New returns a middleware compressing responses whose Content-Type matches
+// config.ContentTypes with the best encoding accepted by the client.
+func New(config Config) func(http.Handler) http.Handler {
+	gzipPool := sync.Pool{New: func() any {
+		writer, err := gzip.NewWriterLevel(io.Discard, config.GzipLevel)
+		if err != nil {
+			writer = gzip.NewWriter(io.Discard)
+		}
+		return writer
+	}}
+	brotliPool := sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(io.Discard, config.BrotliLevel)
+	}}
+
+	return func(next http.Handler) http.Handler {
+		if !config.Enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := config.negotiate(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			writer := &compressWriter{
+				ResponseWriter: w,
+				config:         &config,
+				encoding:       encoding,
+				status:         http.StatusOK,
+				newEncoder: func(out io.Writer) encoder {
+					if encoding == "br" {
+						brotliWriter := brotliPool.Get().(*brotli.Writer)
+						brotliWriter.Reset(out)
+						return pooledEncoder{brotliWriter, func() { brotliPool.Put(brotliWriter) }}
+					}
+					gzipWriter := gzipPool.Get().(*gzip.Writer)
+					gzipWriter.Reset(out)
+					return pooledEncoder{gzipWriter, func() { gzipPool.Put(gzipWriter) }}
+				},
+			}
+			defer writer.close()
+			next.ServeHTTP(writer, r)
+		})
+	}
+}
+
+// negotiate picks the first configured algorithm accepted by the client.
+func (config Config) negotiate(acceptEncoding string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = parsed
+			}
+		}
+		accepted[strings.ToLower(name)] = quality > 0
+	}
+	for _, algorithm := range config.Algorithms {
+		if accepted[algorithm] {
+			return algorithm
+		}
+	}
+	return ""
+}
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// pooledEncoder returns its writer to the pool once closed.
+type pooledEncoder struct {
+	encoder
+	release func()
+}
+
+func (pooled pooledEncoder) Close() error {
+	err := pooled.encoder.Close()
+	pooled.release()
+	return err
+}
+
+// compressWriter buffers the first MinSize bytes to decide whether the
+// response is worth compressing, then streams through the encoder.
+type compressWriter struct {
+	http.ResponseWriter
+	config      *Config
+	encoding    string
+	newEncoder  func(io.Writer) encoder
+	encoder     encoder
+	status      int
+	buffer      []byte
+	decided     bool
+	wroteHeader bool
+}
+
+func (writer *compressWriter) WriteHeader(status int) {
+	if writer.wroteHeader || writer.decided {
+		return
+	}
+	writer.wroteHeader = true
+	writer.status = status
+	// Responses without a body are never compressed
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		writer.passthrough()
+	}
+}
+
+func (writer *compressWriter) Write(data []byte) (int, error) {
+	if !writer.decided {
+		writer.buffer = append(writer.buffer, data...)
+		if len(writer.buffer) < writer.config.MinSize {
+			return len(data), nil
+		}
+		if err := writer.decide(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if writer.encoder != nil {
+		return writer.encoder.Write(data)
+	}
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *compressWriter) Flush() {
+	if !writer.decided {
+		writer.decide()
+	}
+	if writer.encoder != nil {
+		writer.encoder.Flush()
+	}
+	if flusher, ok := writer.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (writer *compressWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// decide starts compressing or falls back to a plain response, then writes
+// whatever was buffered so far.
+func (writer *compressWriter) decide() error {
+	if !writer.isCompressible() {
+		return writer.passthrough()
+	}
+
+	writer.decided = true
+	header := writer.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Encoding", writer.encoding)
+	// The compressed body is no longer byte-identical to the hashed one
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+	writer.ResponseWriter.WriteHeader(writer.status)
+
+	writer.encoder = writer.newEncoder(writer.ResponseWriter)
+	_, err := writer.encoder.Write(writer.buffer)
+	writer.buffer = nil
+	return err
+}
+
+func (writer *compressWriter) passthrough() error {
+	writer.decided = true
+	writer.ResponseWriter.WriteHeader(writer.status)
+	_, err := writer.ResponseWriter.Write(writer.buffer)
+	writer.buffer = nil
+	return err
+}
+
+func (writer *compressWriter) isCompressible() bool {
+	header := writer.Header()
+	if header.Get("Content-Encoding") != "" || len(writer.buffer) == 0 {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(writer.buffer)
+		header.Set("Content-Type", contentType)
+	}
+	for _, allowed := range writer.config.ContentTypes {
+		if strings.HasPrefix(contentType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (writer *compressWriter) close() {
+	if !writer.decided {
+		// Response ended below MinSize
+		writer.passthrough()
+	}
+	if writer.encoder != nil {
+		writer.encoder.Close()
+	}
+}
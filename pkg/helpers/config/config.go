@@ -0,0 +1,44 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Section decodes the name section of gothic-config.json over def, so fields
+// missing from the file keep their default value. def is deep copied first:
+// decoding a JSON array into a slice shared with a package DefaultConfig
+// would overwrite the default itself.
+func Section[T any](gothicConfig []byte, name string, def T) (T, error) {
+	var section T
+	defaults, err := json.Marshal(def)
+	if err == nil {
+		err = json.Unmarshal(defaults, &section)
+	}
+	if err != nil {
+		return def, fmt.Errorf("error copying %s defaults: %v", name, err)
+	}
+
+	var file map[string]json.RawMessage
+	if err := json.Unmarshal(gothicConfig, &file); err != nil {
+		return section, fmt.Errorf("error decoding %s config: %v", name, err)
+	}
+	raw, found := file[name]
+	if !found {
+		// Like struct fields, section names match case-insensitively
+		for key, value := range file {
+			if strings.EqualFold(key, name) {
+				raw, found = value, true
+				break
+			}
+		}
+	}
+	if !found {
+		return section, nil
+	}
+	if err := json.Unmarshal(raw, &section); err != nil {
+		return section, fmt.Errorf("error decoding %s config: %v", name, err)
+	}
+	return section, nil
+}
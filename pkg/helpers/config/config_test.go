@@ -0,0 +1,79 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testConfig struct {
+	Enabled bool     `json:"enabled"`
+	Name    string   `json:"name"`
+	List    []string `json:"list"`
+}
+
+func TestSection(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    testConfig
+		wantErr bool
+	}{
+		{
+			name: "missing section keeps the defaults",
+			file: `{"other": {"enabled": false}}`,
+			want: testConfig{Enabled: true, Name: "default", List: []string{"a", "b"}},
+		},
+		{
+			name: "missing fields keep their default",
+			file: `{"test": {"name": "custom"}}`,
+			want: testConfig{Enabled: true, Name: "custom", List: []string{"a", "b"}},
+		},
+		{
+			name: "arrays replace the default",
+			file: `{"test": {"list": ["c"]}}`,
+			want: testConfig{Enabled: true, Name: "default", List: []string{"c"}},
+		},
+		{
+			name: "section names match case-insensitively",
+			file: `{"TEST": {"enabled": false}}`,
+			want: testConfig{Enabled: false, Name: "default", List: []string{"a", "b"}},
+		},
+		{
+			name: "null section keeps the defaults",
+			file: `{"test": null}`,
+			want: testConfig{Enabled: true, Name: "default", List: []string{"a", "b"}},
+		},
+		{
+			name:    "invalid file",
+			file:    `{"test": `,
+			wantErr: true,
+		},
+		{
+			name:    "invalid section",
+			file:    `{"test": {"enabled": "yes"}}`,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			def := testConfig{Enabled: true, Name: "default", List: []string{"a", "b"}}
+			got, err := Section([]byte(test.file), "test", def)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Section() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(def.List, []string{"a", "b"}) {
+				t.Errorf("Section() modified the default list: %v", def.List)
+			}
+			if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Section() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSectionScalar(t *testing.T) {
+	got, err := Section([]byte(`{"projectName": "gothic-example"}`), "projectName", "")
+	if err != nil || got != "gothic-example" {
+		t.Errorf(`Section() = %q, %v, want "gothic-example", nil`, got, err)
+	}
+}
@@ -0,0 +1,177 @@
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/felipegenef/gothicframework/pkg/helpers/config"
+)
+
+// Config is the "cors" section of gothic-config.json. ApiRouteConfig.CORS
+// overrides it for a single route.
+type Config struct {
+	// AllowedOrigins lists origins such as "https://example.com", patterns
+	// such as "https://*.example.com", or "*" for any origin. CORS is off
+	// while it is empty. "*" can't be combined with AllowCredentials, which
+	// would let any site act on behalf of the logged in user.
+	AllowedOrigins []string `json:"allowedOrigins"`
+	// AllowedMethods restricts the methods accepted in preflights. Empty
+	// allows the method of the route.
+	AllowedMethods   []string `json:"allowedMethods"`
+	AllowedHeaders   []string `json:"allowedHeaders"`
+	ExposedHeaders   []string `json:"exposedHeaders"`
+	AllowCredentials bool     `json:"allowCredentials"`
+	MaxAgeInSec      int      `json:"maxAgeInSec"`
+}
+
+var DefaultConfig = Config{
+	AllowedOrigins: []string{},
+	AllowedMethods: []string{},
+	AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+	ExposedHeaders: []string{},
+	MaxAgeInSec:    600,
+}
+
+var defaultConfig atomic.Pointer[Config]
+
+func init() {
+	defaultConfig.Store(&DefaultConfig)
+}
+
+// LoadConfig reads the "cors" section of gothic-config.json, used by API
+// routes without their own CORS, and rejects it when Validate fails.
+func LoadConfig(gothicConfig []byte) (Config, error) {
+	cors, err := config.Section(gothicConfig, "cors", DefaultConfig)
+	if err != nil {
+		return cors, err
+	}
+	return cors, cors.Validate()
+}
+
+// Validate rejects configs allowing credentialed requests from any origin.
+func (config Config) Validate() error {
+	if config.AllowCredentials && slices.Contains(config.AllowedOrigins, "*") {
+		return fmt.Errorf(`cors.allowedOrigins can't contain "*" while allowCredentials is set, list the trusted origins instead`)
+	}
+	return nil
+}
+
+// SetDefault makes config the one used by API routes without their own. Call
+// it before registering routes.
+func SetDefault(config Config) {
+	defaultConfig.Store(&config)
+}
+
+// Default returns the config set by SetDefault.
+func Default() Config {
+	return *defaultConfig.Load()
+}
+
+// Enabled reports whether any origin is allowed.
+func (config Config) Enabled() bool {
+	return len(config.AllowedOrigins) > 0
+}
+
+// Handler adds the CORS headers to responses for allowed origins.
+func (config Config) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); config.allowsOrigin(origin) {
+			config.setAllowOrigin(w, origin)
+			if len(config.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(config.ExposedHeaders, ", "))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Preflight answers OPTIONS requests for a route accepting method. Rejected
+// preflights get no CORS headers, which makes the browser block the request.
+func (config Config) Preflight(method string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Add("Vary", "Origin")
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+
+		origin := r.Header.Get("Origin")
+		requestedMethod := r.Header.Get("Access-Control-Request-Method")
+		if !config.allowsOrigin(origin) || !config.allowsMethod(method, requestedMethod) || !config.allowsHeaders(r.Header.Get("Access-Control-Request-Headers")) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		config.setAllowOrigin(w, origin)
+		header.Set("Access-Control-Allow-Methods", method)
+		if requestedHeaders := r.Header.Get("Access-Control-Request-Headers"); requestedHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", requestedHeaders)
+		}
+		if config.MaxAgeInSec > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(config.MaxAgeInSec))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (config Config) setAllowOrigin(w http.ResponseWriter, origin string) {
+	if slices.Contains(config.AllowedOrigins, "*") && !config.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if config.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (config Config) allowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range config.AllowedOrigins {
+		// Configs skipping Validate still never echo any origin with credentials
+		if allowed == "*" {
+			if !config.AllowCredentials {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if prefix, suffix, found := strings.Cut(allowed, "*"); found &&
+			len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (config Config) allowsMethod(routeMethod string, requestedMethod string) bool {
+	if requestedMethod != routeMethod {
+		return false
+	}
+	return len(config.AllowedMethods) == 0 || slices.Contains(config.AllowedMethods, routeMethod)
+}
+
+// allowsHeaders checks a comma separated Access-Control-Request-Headers list.
+func (config Config) allowsHeaders(requested string) bool {
+	for _, name := range strings.Split(requested, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.ContainsFunc(config.AllowedHeaders, func(allowed string) bool {
+			return allowed == "*" || strings.EqualFold(allowed, name)
+		}) {
+			return false
+		}
+	}
+	return true
+}
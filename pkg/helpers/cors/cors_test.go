@@ -0,0 +1,136 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowsOrigin(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowed     []string
+		credentials bool
+		origin      string
+		want        bool
+	}{
+		{name: "exact match", allowed: []string{"https://example.com"}, origin: "https://example.com", want: true},
+		{name: "case insensitive", allowed: []string{"https://Example.com"}, origin: "https://example.com", want: true},
+		{name: "other origin", allowed: []string{"https://example.com"}, origin: "https://evil.com", want: false},
+		{name: "other scheme", allowed: []string{"https://example.com"}, origin: "http://example.com", want: false},
+		{name: "empty origin", allowed: []string{"*"}, origin: "", want: false},
+		{name: "no allowed origins", allowed: []string{}, origin: "https://example.com", want: false},
+		{name: "any origin", allowed: []string{"*"}, origin: "https://evil.com", want: true},
+		{name: "any origin with credentials", allowed: []string{"*"}, credentials: true, origin: "https://evil.com", want: false},
+		{name: "explicit origin with credentials", allowed: []string{"*", "https://example.com"}, credentials: true, origin: "https://example.com", want: true},
+		{name: "subdomain pattern", allowed: []string{"https://*.example.com"}, origin: "https://app.example.com", want: true},
+		{name: "nested subdomain pattern", allowed: []string{"https://*.example.com"}, origin: "https://a.b.example.com", want: true},
+		{name: "pattern needs a subdomain", allowed: []string{"https://*.example.com"}, origin: "https://example.com", want: false},
+		{name: "pattern with empty wildcard", allowed: []string{"https://*.example.com"}, origin: "https://.example.com", want: false},
+		{name: "pattern suffix attack", allowed: []string{"https://*.example.com"}, origin: "https://app.example.com.evil.com", want: false},
+		{name: "pattern lookalike domain", allowed: []string{"https://*.example.com"}, origin: "https://evilexample.com", want: false},
+		{name: "pattern other scheme", allowed: []string{"https://*.example.com"}, origin: "http://app.example.com", want: false},
+		{name: "port pattern", allowed: []string{"http://localhost:*"}, origin: "http://localhost:3000", want: true},
+		{name: "port pattern without port", allowed: []string{"http://localhost:*"}, origin: "http://localhost", want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Config{AllowedOrigins: test.allowed, AllowCredentials: test.credentials}
+			if got := config.allowsOrigin(test.origin); got != test.want {
+				t.Errorf("allowsOrigin(%q) = %v, want %v", test.origin, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr bool
+	}{
+		{name: "defaults", file: `{}`},
+		{name: "any origin", file: `{"cors": {"allowedOrigins": ["*"]}}`},
+		{name: "listed origins with credentials", file: `{"cors": {"allowedOrigins": ["https://example.com"], "allowCredentials": true}}`},
+		{name: "any origin with credentials", file: `{"cors": {"allowedOrigins": ["https://example.com", "*"], "allowCredentials": true}}`, wantErr: true},
+		{name: "invalid json", file: `{"cors": `, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := LoadConfig([]byte(test.file)); (err != nil) != test.wantErr {
+				t.Errorf("LoadConfig() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          Config
+		origin          string
+		wantOrigin      string
+		wantCredentials string
+	}{
+		{name: "any origin", config: Config{AllowedOrigins: []string{"*"}}, origin: "https://example.com", wantOrigin: "*"},
+		{name: "listed origin", config: Config{AllowedOrigins: []string{"https://example.com"}}, origin: "https://example.com", wantOrigin: "https://example.com"},
+		{name: "credentials", config: Config{AllowedOrigins: []string{"https://example.com"}, AllowCredentials: true}, origin: "https://example.com", wantOrigin: "https://example.com", wantCredentials: "true"},
+		{name: "any origin with credentials", config: Config{AllowedOrigins: []string{"*"}, AllowCredentials: true}, origin: "https://evil.com"},
+		{name: "rejected origin", config: Config{AllowedOrigins: []string{"https://example.com"}}, origin: "https://evil.com"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := test.config.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			request := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+			request.Header.Set("Origin", test.origin)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != test.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, test.wantOrigin)
+			}
+			if got := recorder.Header().Get("Access-Control-Allow-Credentials"); got != test.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, test.wantCredentials)
+			}
+		})
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	config := DefaultConfig
+	config.AllowedOrigins = []string{"https://example.com"}
+	tests := []struct {
+		name       string
+		origin     string
+		method     string
+		headers    string
+		wantAllow  bool
+		wantMaxAge string
+	}{
+		{name: "allowed", origin: "https://example.com", method: http.MethodPost, headers: "Content-Type, X-CSRF-Token", wantAllow: true, wantMaxAge: "600"},
+		{name: "other method", origin: "https://example.com", method: http.MethodDelete},
+		{name: "other origin", origin: "https://evil.com", method: http.MethodPost},
+		{name: "unlisted header", origin: "https://example.com", method: http.MethodPost, headers: "X-Secret"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodOptions, "/api/items", nil)
+			request.Header.Set("Origin", test.origin)
+			request.Header.Set("Access-Control-Request-Method", test.method)
+			request.Header.Set("Access-Control-Request-Headers", test.headers)
+			recorder := httptest.NewRecorder()
+			config.Preflight(http.MethodPost).ServeHTTP(recorder, request)
+
+			if recorder.Code != http.StatusNoContent {
+				t.Errorf("status = %d, want 204", recorder.Code)
+			}
+			allowed := recorder.Header().Get("Access-Control-Allow-Origin") != ""
+			if allowed != test.wantAllow {
+				t.Errorf("allowed = %v, want %v", allowed, test.wantAllow)
+			}
+			if got := recorder.Header().Get("Access-Control-Max-Age"); got != test.wantMaxAge {
+				t.Errorf("Access-Control-Max-Age = %q, want %q", got, test.wantMaxAge)
+			}
+		})
+	}
+}
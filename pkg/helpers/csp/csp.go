@@ -0,0 +1,163 @@
+package csp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/felipegenef/gothicframework/pkg/helpers/config"
+	routes "github.com/felipegenef/gothicframework/pkg/helpers/routes"
+)
+
+// Config is the "csp" section of gothic-config.json.
+type Config struct {
+	Enabled bool `json:"enabled"`
+	// Policy is the header value. Every {nonce} is replaced by the nonce of the
+	// request.
+	Policy string `json:"policy"`
+	// SharedPolicy is sent with STATIC and ISR pages instead. CloudFront hands
+	// their markup to every visitor, so it can't carry a nonce: it lists the
+	// URLs, or the hashes, of the scripts and styles those pages may use.
+	SharedPolicy string `json:"sharedPolicy"`
+	// ReportOnly sends Content-Security-Policy-Report-Only instead, to try a
+	// policy without blocking anything.
+	ReportOnly bool `json:"reportOnly"`
+}
+
+// DefaultPolicy only runs scripts carrying the request nonce. 'strict-dynamic'
+// lets them load further scripts, such as HTMX extensions, and the https:
+// source is ignored by browsers supporting it.
+const DefaultPolicy = "default-src 'self'; " +
+	"script-src 'nonce-{nonce}' 'strict-dynamic' https:; " +
+	"style-src 'self' 'nonce-{nonce}'; " +
+	"img-src 'self' data:; " +
+	"object-src 'none'; " +
+	"base-uri 'self'; " +
+	"form-action 'self'; " +
+	"frame-ancestors 'self'"
+
+// DefaultSharedPolicy allows the scripts of the default layout on STATIC and
+// ISR pages, listed one by one since there is no nonce to trust them with.
+// Same origin files, such as the csrf.Bootstrap script, are allowed by 'self'.
+const DefaultSharedPolicy = "default-src 'self'; " +
+	"script-src 'self' https://unpkg.com/htmx.org@2.0.3 https://unpkg.com/hx-ext-amz-content-sha256@1.0.3/min.js; " +
+	"style-src 'self'; " +
+	"img-src 'self' data:; " +
+	"object-src 'none'; " +
+	"base-uri 'self'; " +
+	"form-action 'self'; " +
+	"frame-ancestors 'self'"
+
+var DefaultConfig = Config{
+	Enabled:      true,
+	Policy:       DefaultPolicy,
+	SharedPolicy: DefaultSharedPolicy,
+}
+
+// LoadConfig reads the "csp" section of gothic-config.json. Files written
+// before SharedPolicy existed keep DefaultSharedPolicy for STATIC and ISR
+// pages.
+func LoadConfig(gothicConfig []byte) (Config, error) {
+	return config.Section(gothicConfig, "csp", DefaultConfig)
+}
+
+// New returns a middleware generating a nonce for every request and sending
+// the policy header. The nonce is stored with templ.WithNonce, so templates
+// read it with templ.GetNonce(ctx) or Nonce(ctx), and templ script helpers
+// use it on their own.
+//
+// STATIC and ISR pages are rendered without a nonce and get SharedPolicy, see
+// routes.SharedPolicyWriter.
+func New(config Config) func(http.Handler) http.Handler {
+	headerName := "Content-Security-Policy"
+	if config.ReportOnly {
+		headerName = "Content-Security-Policy-Report-Only"
+	}
+	return func(next http.Handler) http.Handler {
+		if !config.Enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce := newNonce()
+			writer := &policyWriter{
+				ResponseWriter: w,
+				headerName:     headerName,
+				policy:         strings.ReplaceAll(config.Policy, "{nonce}", nonce),
+				sharedPolicy:   config.SharedPolicy,
+			}
+			next.ServeHTTP(writer, r.WithContext(templ.WithNonce(r.Context(), nonce)))
+		})
+	}
+}
+
+// Nonce returns the nonce of the current request, or an empty string.
+func Nonce(ctx context.Context) string {
+	return templ.GetNonce(ctx)
+}
+
+// HtmxConfig returns the content of the htmx-config meta tag, making HTMX use
+// the nonce on the scripts and styles it inserts:
+//
+//	<meta name="htmx-config" content={ csp.HtmxConfig(ctx) }/>
+func HtmxConfig(ctx context.Context) string {
+	nonce := Nonce(ctx)
+	config, _ := json.Marshal(map[string]string{
+		"inlineScriptNonce": nonce,
+		"inlineStyleNonce":  nonce,
+	})
+	return string(config)
+}
+
+func newNonce() string {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	return base64.StdEncoding.EncodeToString(nonce)
+}
+
+// policyWriter sets the policy header once the status is known. 304 responses
+// don't carry it, see the page identity comment of the routes package.
+type policyWriter struct {
+	http.ResponseWriter
+	headerName   string
+	policy       string
+	sharedPolicy string
+	wroteHeader  bool
+}
+
+var _ routes.SharedPolicyWriter = (*policyWriter)(nil)
+
+// UseSharedPolicy switches the response to SharedPolicy.
+func (writer *policyWriter) UseSharedPolicy() {
+	writer.policy = writer.sharedPolicy
+}
+
+func (writer *policyWriter) WriteHeader(status int) {
+	if !writer.wroteHeader {
+		writer.wroteHeader = true
+		if status != http.StatusNotModified {
+			writer.Header().Set(writer.headerName, writer.policy)
+		}
+	}
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+func (writer *policyWriter) Write(data []byte) (int, error) {
+	if !writer.wroteHeader {
+		writer.WriteHeader(http.StatusOK)
+	}
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *policyWriter) Flush() {
+	if flusher, ok := writer.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (writer *policyWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
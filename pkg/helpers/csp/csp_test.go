@@ -0,0 +1,53 @@
+package csp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ"
+	routes "github.com/felipegenef/gothicframework/pkg/helpers/routes"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestPolicyOfRoutes(t *testing.T) {
+	tests := []struct {
+		name       string
+		configType routes.ConfigType
+		wantShared bool
+	}{
+		{name: "static", configType: routes.STATIC, wantShared: true},
+		{name: "isr", configType: routes.ISR, wantShared: true},
+		{name: "dynamic", configType: routes.DYNAMIC, wantShared: false},
+	}
+	t.Setenv("LOCAL_SERVE", "")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := routes.RouteConfig[string]{Type: test.configType, HttpMethod: routes.GET, RevalidateInSec: 60, Middleware: func(w http.ResponseWriter, r *http.Request) string {
+				return ""
+			}}
+			router := chi.NewRouter()
+			router.Use(New(DefaultConfig))
+			config.RegisterRoute(router, "/page", func(props string) templ.Component {
+				// An injected inline script must not be allowed by the policy
+				return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+					_, err := io.WriteString(w, "<script>alert(1)</script>")
+					return err
+				})
+			})
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/page", nil))
+
+			policy := recorder.Header().Get("Content-Security-Policy")
+			if test.wantShared && policy != DefaultSharedPolicy {
+				t.Errorf("policy = %q, want DefaultSharedPolicy", policy)
+			}
+			if !test.wantShared && !strings.Contains(policy, "'nonce-") {
+				t.Errorf("policy = %q, want a nonce", policy)
+			}
+		})
+	}
+}
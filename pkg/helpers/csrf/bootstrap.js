@@ -0,0 +1,26 @@
+// Served on the TokenPath of the csrf config followed by ".js" and loaded by
+// the Bootstrap component of STATIC and ISR pages. It fetches the visitor
+// token, fills the empty hidden inputs of Field and adds it to HTMX requests,
+// holding back the ones fired before it arrives.
+(function () {
+	var csrf;
+	var loaded = false;
+	var token = fetch(document.currentScript.dataset.tokenPath, { credentials: "same-origin" })
+		.then(function (response) { return response.json(); })
+		.then(function (result) {
+			csrf = result;
+			document.querySelectorAll("input[type=hidden][name='" + result.fieldName + "']").forEach(function (input) {
+				if (!input.value) input.value = result.token;
+			});
+		})
+		.catch(function () {})
+		.then(function () { loaded = true; });
+	document.addEventListener("htmx:confirm", function (event) {
+		if (loaded) return;
+		event.preventDefault();
+		token.then(function () { event.detail.issueRequest(false); });
+	});
+	document.addEventListener("htmx:configRequest", function (event) {
+		if (csrf) event.detail.headers[csrf.headerName] = csrf.token;
+	});
+})();
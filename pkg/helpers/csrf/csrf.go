@@ -0,0 +1,291 @@
+package csrf
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/felipegenef/gothicframework/pkg/helpers/config"
+	routes "github.com/felipegenef/gothicframework/pkg/helpers/routes"
+)
+
+// Config is the "csrf" section of gothic-config.json.
+type Config struct {
+	Enabled    bool   `json:"enabled"`
+	CookieName string `json:"cookieName"`
+	HeaderName string `json:"headerName"`
+	FieldName  string `json:"fieldName"`
+	Secure     bool   `json:"secure"`
+	// ExemptPaths lists path prefixes never checked, e.g. webhooks.
+	ExemptPaths []string `json:"exemptPaths"`
+	// BearerAuthPaths lists path prefixes skipped when the request carries an
+	// "Authorization: Bearer" header. Browsers never add that header on their
+	// own, so those requests can't be forged by another site.
+	BearerAuthPaths []string `json:"bearerAuthPaths"`
+	// TokenPath answers GET requests with a token of the visitor, for the
+	// STATIC and ISR pages that can't render one. Bootstrap fetches it with
+	// the script served on TokenPath followed by ".js".
+	TokenPath string `json:"tokenPath"`
+}
+
+var DefaultConfig = Config{
+	Enabled:         true,
+	CookieName:      "gothic_csrf",
+	HeaderName:      "X-CSRF-Token",
+	FieldName:       "csrf_token",
+	Secure:          true,
+	ExemptPaths:     []string{},
+	BearerAuthPaths: []string{"/api/"},
+	TokenPath:       "/_gothicframework/csrf",
+}
+
+const tokenLength = 32
+
+//go:embed bootstrap.js
+var bootstrapScript []byte
+
+// scriptPolicy lets browsers and CloudFront keep the Bootstrap script, which
+// only changes with the framework.
+var scriptPolicy = routes.CachePolicy{MaxAgeInSec: 3600, SMaxAgeInSec: 31536000}
+
+type contextKey struct{}
+
+// requestToken is the CSRF state of a request, read by Token.
+type requestToken struct {
+	config *Config
+	secret []byte
+}
+
+// LoadConfig reads the "csrf" section of gothic-config.json. The server turns
+// Secure off under LOCAL_SERVE, browsers drop Secure cookies over plain HTTP.
+func LoadConfig(gothicConfig []byte) (Config, error) {
+	return config.Section(gothicConfig, "csrf", DefaultConfig)
+}
+
+// New returns a middleware rejecting POST, PUT, PATCH and DELETE requests
+// without a valid token with 403 Forbidden. The token is read from the
+// HeaderName header, sent by HTMX through the layout hx-headers, or from the
+// FieldName form field rendered by Field.
+//
+// Each browser gets a random secret in an HttpOnly cookie. Rendered tokens are
+// masked with a fresh one-time pad, so compressed pages don't leak the secret
+// through their size (BREACH).
+func New(config Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !config.Enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret, hasCookie := config.readSecret(r)
+			if !hasCookie {
+				secret = make([]byte, tokenLength)
+				rand.Read(secret)
+				w = &cookieWriter{ResponseWriter: w, cookie: config.cookie(secret)}
+			}
+
+			if !isSafeMethod(r.Method) && !config.isExempt(r) {
+				token := r.Header.Get(config.HeaderName)
+				if token == "" {
+					token = r.PostFormValue(config.FieldName)
+				}
+				if !hasCookie || !validToken(token, secret) {
+					http.Error(w, "invalid CSRF token", http.StatusForbidden)
+					return
+				}
+			}
+
+			if r.Method == http.MethodGet && config.TokenPath != "" {
+				switch r.URL.Path {
+				case config.TokenPath:
+					config.serveToken(w, secret)
+					return
+				case scriptPath(config.TokenPath):
+					serveScript(w)
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), contextKey{}, &requestToken{config: &config, secret: secret})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Token returns a masked token for the current request. It is empty when the
+// middleware is disabled and while rendering STATIC or ISR pages, whose
+// markup is shared by every visitor: Bootstrap fills their forms and HTMX
+// requests in the browser instead.
+func Token(ctx context.Context) string {
+	state, ok := ctx.Value(contextKey{}).(*requestToken)
+	if !ok || routes.IsSharedRender(ctx) {
+		return ""
+	}
+	return maskToken(state.secret)
+}
+
+// HxHeaders returns the value of an hx-headers attribute sending the token
+// with every HTMX request, e.g. <body hx-headers={ csrf.HxHeaders(ctx) }>.
+func HxHeaders(ctx context.Context) string {
+	headerName := DefaultConfig.HeaderName
+	if state, ok := ctx.Value(contextKey{}).(*requestToken); ok {
+		headerName = state.config.HeaderName
+	}
+	headers, _ := json.Marshal(map[string]string{headerName: Token(ctx)})
+	return string(headers)
+}
+
+// tokenResponse is the body served on TokenPath and read by Bootstrap.
+type tokenResponse struct {
+	Token      string `json:"token"`
+	HeaderName string `json:"headerName"`
+	FieldName  string `json:"fieldName"`
+}
+
+func (config *Config) serveToken(w http.ResponseWriter, secret []byte) {
+	w.Header().Set("Cache-Control", routes.DefaultDynamicPolicy.String())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokenResponse{
+		Token:      maskToken(secret),
+		HeaderName: config.HeaderName,
+		FieldName:  config.FieldName,
+	})
+}
+
+func serveScript(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", scriptPolicy.String())
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Write(bootstrapScript)
+}
+
+// scriptPath is where the Bootstrap script of tokenPath is served.
+func scriptPath(tokenPath string) string {
+	return tokenPath + ".js"
+}
+
+// bootstrapPath returns the TokenPath Bootstrap fetches, or an empty string
+// when the page doesn't need it.
+func bootstrapPath(ctx context.Context) string {
+	state, ok := ctx.Value(contextKey{}).(*requestToken)
+	if !ok || !routes.IsSharedRender(ctx) {
+		return ""
+	}
+	return state.config.TokenPath
+}
+
+func fieldName(ctx context.Context) string {
+	if state, ok := ctx.Value(contextKey{}).(*requestToken); ok {
+		return state.config.FieldName
+	}
+	return DefaultConfig.FieldName
+}
+
+func (config *Config) readSecret(r *http.Request) ([]byte, bool) {
+	cookie, err := r.Cookie(config.CookieName)
+	if err != nil {
+		return nil, false
+	}
+	secret, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(secret) != tokenLength {
+		return nil, false
+	}
+	return secret, true
+}
+
+func (config *Config) cookie(secret []byte) *http.Cookie {
+	return &http.Cookie{
+		Name:     config.CookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(secret),
+		Path:     "/",
+		Secure:   config.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func (config *Config) isExempt(r *http.Request) bool {
+	for _, prefix := range config.ExemptPaths {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		return false
+	}
+	for _, prefix := range config.BearerAuthPaths {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// maskToken returns base64(pad + pad XOR secret).
+func maskToken(secret []byte) string {
+	masked := make([]byte, 2*tokenLength)
+	rand.Read(masked[:tokenLength])
+	for i := range secret {
+		masked[tokenLength+i] = masked[i] ^ secret[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+func validToken(token string, secret []byte) bool {
+	masked, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(masked) != 2*tokenLength {
+		return false
+	}
+	unmasked := make([]byte, tokenLength)
+	for i := range unmasked {
+		unmasked[i] = masked[i] ^ masked[tokenLength+i]
+	}
+	return subtle.ConstantTimeCompare(unmasked, secret) == 1
+}
+
+// cookieWriter sets the CSRF cookie of new visitors, unless the response is
+// cached by CloudFront, which would hand the same secret to everyone.
+type cookieWriter struct {
+	http.ResponseWriter
+	cookie      *http.Cookie
+	wroteHeader bool
+}
+
+func (writer *cookieWriter) WriteHeader(status int) {
+	if !writer.wroteHeader {
+		writer.wroteHeader = true
+		if cacheControl := writer.Header().Get("Cache-Control"); !strings.Contains(cacheControl, "public") {
+			http.SetCookie(writer.ResponseWriter, writer.cookie)
+		}
+	}
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+func (writer *cookieWriter) Write(data []byte) (int, error) {
+	if !writer.wroteHeader {
+		writer.WriteHeader(http.StatusOK)
+	}
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *cookieWriter) Flush() {
+	if flusher, ok := writer.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (writer *cookieWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
@@ -0,0 +1,175 @@
+package csrf
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func testSecret(fill byte) []byte {
+	return bytes.Repeat([]byte{fill}, tokenLength)
+}
+
+func TestMaskToken(t *testing.T) {
+	secret := testSecret(1)
+	first, second := maskToken(secret), maskToken(secret)
+	if first == second {
+		t.Error("maskToken() returned the same token twice, the pad must be random")
+	}
+	if strings.Contains(first, base64.RawURLEncoding.EncodeToString(secret)) {
+		t.Error("maskToken() leaked the secret")
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		secret []byte
+		want   bool
+	}{
+		{name: "first token", token: first, secret: secret, want: true},
+		{name: "second token", token: second, secret: secret, want: true},
+		{name: "other secret", token: first, secret: testSecret(2), want: false},
+		{name: "unmasked secret", token: base64.RawURLEncoding.EncodeToString(secret), secret: secret, want: false},
+		{name: "truncated", token: first[:len(first)-4], secret: secret, want: false},
+		{name: "invalid base64", token: "not base64!", secret: secret, want: false},
+		{name: "empty", token: "", secret: secret, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := validToken(test.token, test.secret); got != test.want {
+				t.Errorf("validToken() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	config := DefaultConfig
+	config.ExemptPaths = []string{"/webhooks/"}
+	secret := testSecret(3)
+	cookie := config.cookie(secret)
+	form := url.Values{config.FieldName: {maskToken(secret)}}.Encode()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		cookie     *http.Cookie
+		header     map[string]string
+		body       string
+		wantStatus int
+	}{
+		{name: "safe method", method: http.MethodGet, path: "/", wantStatus: http.StatusOK},
+		{name: "missing cookie", method: http.MethodPost, path: "/api/login", header: map[string]string{config.HeaderName: maskToken(secret)}, wantStatus: http.StatusForbidden},
+		{name: "missing token", method: http.MethodPost, path: "/api/login", cookie: cookie, wantStatus: http.StatusForbidden},
+		{name: "token of another secret", method: http.MethodDelete, path: "/api/items", cookie: cookie, header: map[string]string{config.HeaderName: maskToken(testSecret(4))}, wantStatus: http.StatusForbidden},
+		{name: "header token", method: http.MethodPut, path: "/api/items", cookie: cookie, header: map[string]string{config.HeaderName: maskToken(secret)}, wantStatus: http.StatusOK},
+		{name: "form token", method: http.MethodPost, path: "/api/login", cookie: cookie, header: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, body: form, wantStatus: http.StatusOK},
+		{name: "exempt path", method: http.MethodPost, path: "/webhooks/stripe", wantStatus: http.StatusOK},
+		{name: "bearer auth api", method: http.MethodPost, path: "/api/items", header: map[string]string{"Authorization": "Bearer key"}, wantStatus: http.StatusOK},
+		{name: "bearer auth page", method: http.MethodPost, path: "/account", header: map[string]string{"Authorization": "Bearer key"}, wantStatus: http.StatusForbidden},
+	}
+	handler := New(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			if test.cookie != nil {
+				request.AddCookie(test.cookie)
+			}
+			for name, value := range test.header {
+				request.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != test.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, test.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCookieOnSharedResponses(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		wantCookie   bool
+	}{
+		{name: "dynamic page", cacheControl: "private, no-store", wantCookie: true},
+		{name: "cached page", cacheControl: "public, max-age=0, s-maxage=31536000", wantCookie: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := New(DefaultConfig)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Cache-Control", test.cacheControl)
+				w.Write([]byte("page"))
+			}))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+			if gotCookie := recorder.Header().Get("Set-Cookie") != ""; gotCookie != test.wantCookie {
+				t.Errorf("cookie set = %v, want %v", gotCookie, test.wantCookie)
+			}
+		})
+	}
+}
+
+func TestTokenPath(t *testing.T) {
+	handler := New(DefaultConfig)(http.NotFoundHandler())
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, DefaultConfig.TokenPath, nil))
+
+	if recorder.Header().Get("Cache-Control") != "private, no-store" {
+		t.Errorf("Cache-Control = %q, the token must never be cached", recorder.Header().Get("Cache-Control"))
+	}
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want the secret cookie", len(cookies))
+	}
+	secret, err := base64.RawURLEncoding.DecodeString(cookies[0].Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response tokenResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if !validToken(response.Token, secret) {
+		t.Error("the served token doesn't match the cookie")
+	}
+	if response.HeaderName != DefaultConfig.HeaderName || response.FieldName != DefaultConfig.FieldName {
+		t.Errorf("names = %q, %q", response.HeaderName, response.FieldName)
+	}
+}
+
+func TestTokenWithoutMiddleware(t *testing.T) {
+	if token := Token(context.Background()); token != "" {
+		t.Errorf("Token() = %q without the middleware", token)
+	}
+}
+
+func TestBootstrapScript(t *testing.T) {
+	handler := New(DefaultConfig)(http.NotFoundHandler())
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, DefaultConfig.TokenPath+".js", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+	if got := recorder.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/javascript") {
+		t.Errorf("Content-Type = %q, want a script", got)
+	}
+	// The script is the same for everyone, it must not hand out a secret
+	if !strings.HasPrefix(recorder.Header().Get("Cache-Control"), "public") || recorder.Header().Get("Set-Cookie") != "" {
+		t.Errorf("Cache-Control = %q, Set-Cookie = %q", recorder.Header().Get("Cache-Control"), recorder.Header().Get("Set-Cookie"))
+	}
+	if !bytes.Equal(recorder.Body.Bytes(), bootstrapScript) {
+		t.Error("the body is not the bootstrap script")
+	}
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/felipegenef/gothicframework/pkg/helpers/config"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Config is the "metrics" section of gothic-config.json.
+type Config struct {
+	// Enabled serves the metrics of deployed apps. Anyone reaching the app
+	// can read them, so keep it off unless the path is protected.
+	Enabled bool `json:"enabled"`
+	// Path is where the Prometheus text format is served.
+	Path string `json:"path"`
+}
+
+var DefaultConfig = Config{
+	Enabled: false,
+	Path:    "/metrics",
+}
+
+// unmatchedRoute labels requests that matched no route, so 404 scans can't
+// create a time series per URL.
+const unmatchedRoute = "unmatched"
+
+// Registry holds every Gothic metric. Register your own collectors here to
+// serve them on the same endpoint.
+var Registry = prometheus.NewRegistry()
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gothic_http_requests_total",
+		Help: "HTTP requests served, by route pattern, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gothic_http_request_duration_seconds",
+		Help:    "Time to serve HTTP requests, by route pattern and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	renderDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gothic_render_duration_seconds",
+		Help:    "Time to render templ components, by route pattern and render type.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"route", "render_type"})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gothic_cache_lookups_total",
+		Help: "Local page cache lookups, by route pattern, render type and result (hit, miss or stale).",
+	}, []string{"route", "render_type", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestsTotal,
+		requestDuration,
+		renderDuration,
+		cacheLookups,
+	)
+}
+
+// LoadConfig reads the "metrics" section of gothic-config.json, which tells
+// the server whether to mount Middleware and Handler.
+func LoadConfig(gothicConfig []byte) (Config, error) {
+	return config.Section(gothicConfig, "metrics", DefaultConfig)
+}
+
+// Handler serves Registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware counts requests and observes their latency, labelled by the chi
+// route pattern rather than the raw URL.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		route := RoutePattern(r)
+		requestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Inc()
+		requestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ObserveRender records how long rendering a page component took.
+func ObserveRender(route string, renderType string, duration time.Duration) {
+	renderDuration.WithLabelValues(route, renderType).Observe(duration.Seconds())
+}
+
+// CountCacheLookup records the result of a local page cache lookup.
+func CountCacheLookup(route string, renderType string, result string) {
+	cacheLookups.WithLabelValues(route, renderType, result).Inc()
+}
+
+// RoutePattern returns the chi pattern matched by r, such as "/blog/{slug}".
+func RoutePattern(r *http.Request) string {
+	if routeContext := chi.RouteContext(r.Context()); routeContext != nil && routeContext.RoutePattern() != "" {
+		return routeContext.RoutePattern()
+	}
+	return unmatchedRoute
+}
+
+// statusRecorder captures the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	if !recorder.wroteHeader {
+		recorder.status = status
+		recorder.wroteHeader = true
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Write(data []byte) (int, error) {
+	recorder.wroteHeader = true
+	return recorder.ResponseWriter.Write(data)
+}
+
+func (recorder *statusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
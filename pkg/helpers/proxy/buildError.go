@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BuildError is sent to the browser when the hot reload build fails, so the
+// page shows it in an overlay instead of stale content.
+type BuildError struct {
+	// Stage is the failing step, like "templ" or "go build".
+	Stage    string         `json:"stage"`
+	Problems []BuildProblem `json:"problems"`
+	// Output is the raw compiler output, shown when no problem was parsed.
+	Output string `json:"output"`
+}
+
+type BuildProblem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+var (
+	// src/pages/index.templ parsing error: <div>: unclosed tag: line 12, col 3
+	templErrorRegex = regexp.MustCompile(`(\S+\.templ) parsing error: (.+?): line (\d+), col (\d+)`)
+	// src/pages/index_templ.go:12:3: undefined: name
+	goErrorRegex = regexp.MustCompile(`(?m)^(\S+\.go):(\d+)(?::(\d+))?: (.+)$`)
+	// panic: runtime error: index out of range [recovered]
+	panicRegex = regexp.MustCompile(`(?m)^panic: (.+)$`)
+	// 	/home/me/app/src/pages/index.go:42 +0x1d
+	stackFrameRegex = regexp.MustCompile(`(?m)^\t(\S+\.go):(\d+)(?: \+0x[0-9a-f]+)?$`)
+)
+
+// ParseBuildOutput reads the file, line and message of each error in templ
+// generate or go build output.
+func ParseBuildOutput(stage string, output string) BuildError {
+	buildError := BuildError{Stage: stage, Problems: []BuildProblem{}, Output: output}
+	for _, match := range templErrorRegex.FindAllStringSubmatch(output, -1) {
+		buildError.Problems = append(buildError.Problems, newBuildProblem(match[1], match[3], match[4], match[2]))
+	}
+	for _, match := range goErrorRegex.FindAllStringSubmatch(output, -1) {
+		buildError.Problems = append(buildError.Problems, newBuildProblem(match[1], match[2], match[3], match[4]))
+	}
+	if match := panicRegex.FindStringSubmatch(output); match != nil {
+		buildError.Problems = append(buildError.Problems, parsePanic(match[1], output))
+	}
+	return buildError
+}
+
+// parsePanic points at the first stack frame inside the project, skipping the
+// runtime and dependencies.
+func parsePanic(message string, output string) BuildProblem {
+	for _, frame := range stackFrameRegex.FindAllStringSubmatch(output, -1) {
+		problem := newBuildProblem(frame[1], frame[2], "", "panic: "+message)
+		if !filepath.IsAbs(problem.File) && !strings.HasPrefix(problem.File, "../") {
+			return problem
+		}
+	}
+	return BuildProblem{Message: "panic: " + message}
+}
+
+func newBuildProblem(file, line, column, message string) BuildProblem {
+	// templ reports absolute paths, show them like go build does
+	if wd, err := os.Getwd(); err == nil && filepath.IsAbs(file) {
+		if rel, err := filepath.Rel(wd, file); err == nil {
+			file = rel
+		}
+	}
+	problem := BuildProblem{File: filepath.ToSlash(file), Message: message}
+	problem.Line, _ = strconv.Atoi(line)
+	problem.Column, _ = strconv.Atoi(column)
+	return problem
+}
+
+// SendBuildError shows buildError in every open page, and in pages opened
+// until ClearBuildError is called.
+func (proxy *ProxyHelper) SendBuildError(buildError BuildError) {
+	data, err := json.Marshal(buildError)
+	if err != nil {
+		log.Printf("failed to encode build error: %v\n", err)
+		return
+	}
+	proxy.Sse.hold(&event{Type: "build-error", Data: string(data)})
+}
+
+// ClearBuildError removes the overlay after a successful build.
+func (proxy *ProxyHelper) ClearBuildError() {
+	if proxy.Sse.hold(nil) {
+		proxy.Sse.Send("build-ok", "")
+	}
+}
@@ -0,0 +1,215 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	caCertFile   = "ca.pem"
+	caKeyFile    = "ca-key.pem"
+	leafCertFile = "cert.pem"
+	leafKeyFile  = "key.pem"
+)
+
+// The local CA lasts long enough to be trusted once. Browsers reject server
+// certificates valid for more than 398 days, the leaf is renewed before it
+// expires.
+const (
+	caValidity     = 10 * 365 * 24 * time.Hour
+	leafValidity   = 397 * 24 * time.Hour
+	leafRenewAhead = 30 * 24 * time.Hour
+)
+
+// LocalCertificate is the development certificate of the proxy, signed by a
+// CA created on the developer machine.
+type LocalCertificate struct {
+	Certificate tls.Certificate
+	// CAFile is the certificate to trust in the browser or the system.
+	CAFile string
+	// CAKeyFile signs certificates for any site, it must stay private.
+	CAKeyFile string
+	// NewCA is set when the CA was just created, it is not trusted yet.
+	NewCA bool
+}
+
+// LoadLocalCertificate reads the certificate cached in dir. The CA and the
+// certificate for hosts are created when missing, and the certificate is
+// signed again when it expires soon or misses one of the hosts.
+func LoadLocalCertificate(dir string, hosts []string) (LocalCertificate, error) {
+	local := LocalCertificate{
+		CAFile:    filepath.Join(dir, caCertFile),
+		CAKeyFile: filepath.Join(dir, caKeyFile),
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return local, err
+	}
+
+	ca, caKey, err := readKeyPair(local.CAFile, local.CAKeyFile)
+	if err != nil || time.Now().After(ca.NotAfter) {
+		if ca, caKey, err = createCA(dir); err != nil {
+			return local, fmt.Errorf("creating local CA: %w", err)
+		}
+		local.NewCA = true
+	}
+
+	certFile, keyFile := filepath.Join(dir, leafCertFile), filepath.Join(dir, leafKeyFile)
+	leaf, _, err := readKeyPair(certFile, keyFile)
+	if err != nil || !leafIsValid(leaf, ca, hosts) {
+		if err := createLeaf(certFile, keyFile, ca, caKey, hosts); err != nil {
+			return local, fmt.Errorf("creating certificate: %w", err)
+		}
+	}
+
+	local.Certificate, err = tls.LoadX509KeyPair(certFile, keyFile)
+	return local, err
+}
+
+// TLSConfig serves the certificate over HTTP/2, with HTTP/1.1 as fallback.
+func (local LocalCertificate) TLSConfig() *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{local.Certificate},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+}
+
+func leafIsValid(leaf, ca *x509.Certificate, hosts []string) bool {
+	if time.Now().Add(leafRenewAhead).After(leaf.NotAfter) {
+		return false
+	}
+	// A new CA invalidates the certificates signed by the previous one
+	if leaf.CheckSignatureFrom(ca) != nil {
+		return false
+	}
+	for _, host := range hosts {
+		if leaf.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func createCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+	hostname, _ := os.Hostname()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"Gothic Framework development CA"},
+			CommonName:   "Gothic Framework local CA " + hostname,
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writeKeyPair(filepath.Join(dir, caCertFile), filepath.Join(dir, caKeyFile), der, key); err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(der)
+	return ca, key, err
+}
+
+func createLeaf(certFile, keyFile string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, hosts []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"Gothic Framework development certificate"},
+		},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    time.Now().Add(leafValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	return writeKeyPair(certFile, keyFile, der, key)
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func readKeyPair(certFile, keyFile string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, errors.New("invalid PEM file")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	parsedKey, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, ok := parsedKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, nil, errors.New("unexpected private key type")
+	}
+	return cert, key, nil
+}
+
+func writeKeyPair(certFile, keyFile string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	// The CA key can sign certificates for any site, only its owner may read it
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
@@ -3,10 +3,14 @@ package proxy
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"math"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -33,6 +37,13 @@ type ProxyHelper struct {
 	Target *url.URL
 	p      *httputil.ReverseProxy
 	Sse    *sseHandler
+	// TLSConfig makes RunProxy serve HTTPS, see LoadLocalCertificate
+	TLSConfig *tls.Config
+	// server is set by RunProxy, guarded by m so Shutdown may run at any time
+	m      *sync.Mutex
+	server *http.Server
+	// cancel ends the requests of the server, like open SSE streams
+	cancel context.CancelFunc
 }
 
 // RoundTripper with retries
@@ -49,15 +60,22 @@ type event struct {
 	Data string
 }
 
+// sseBufferSize is how many events wait for a slow connection before newer
+// ones are dropped for it.
+const sseBufferSize = 16
+
 type sseHandler struct {
 	m        *sync.Mutex
 	counter  int64
 	requests map[int64]chan event
+	// held is sent again to every new connection, like a pending build error
+	held *event
 }
 
 func NewProxyHelper() ProxyHelper {
 	return ProxyHelper{
 		Sse: NewsseHandler(),
+		m:   new(sync.Mutex),
 	}
 }
 
@@ -71,9 +89,20 @@ func NewsseHandler() *sseHandler {
 // RunProxy configures and starts the proxy server with bind, port, and target
 func (proxy *ProxyHelper) RunProxy(bind string, port int, target *url.URL) {
 	proxy.Target = target
-	proxy.URL = fmt.Sprintf("http://%s:%d", bind, port)
+	scheme := "http"
+	if proxy.TLSConfig != nil {
+		scheme = "https"
+	}
+	proxy.URL = fmt.Sprintf("%s://%s:%d", scheme, bind, port)
 
 	p := httputil.NewSingleHostReverseProxy(target)
+	director := p.Director
+	// The app only sees plain HTTP, tell it how the browser reached it, for
+	// redirects and secure cookies
+	p.Director = func(r *http.Request) {
+		director(r)
+		r.Header.Set("X-Forwarded-Proto", scheme)
+	}
 	p.ErrorLog = log.New(os.Stderr, "Proxy error: ", 0)
 	p.Transport = &roundTripper{
 		maxRetries:      20,
@@ -86,12 +115,43 @@ func (proxy *ProxyHelper) RunProxy(bind string, port int, target *url.URL) {
 
 	log.Printf("Starting proxy at %s -> %s\n", proxy.URL, target)
 
-	err := http.ListenAndServe(fmt.Sprintf("%s:%d", bind, port), proxy)
-	if err != nil {
+	ctx, cancel := context.WithCancel(context.Background())
+	server := &http.Server{
+		Addr:        fmt.Sprintf("%s:%d", bind, port),
+		Handler:     proxy,
+		TLSConfig:   proxy.TLSConfig,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	proxy.m.Lock()
+	proxy.server = server
+	proxy.cancel = cancel
+	proxy.m.Unlock()
+
+	var err error
+	if server.TLSConfig != nil {
+		// The certificate comes from the TLS config
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
 
+// Shutdown stops the proxy started by RunProxy, closing the SSE streams that
+// would otherwise keep it open.
+func (proxy *ProxyHelper) Shutdown(ctx context.Context) error {
+	proxy.m.Lock()
+	server, cancel := proxy.server, proxy.cancel
+	proxy.m.Unlock()
+	if server == nil {
+		return nil
+	}
+	cancel()
+	return server.Shutdown(ctx)
+}
+
 // ServeHTTP handles internal routes and normal proxying
 func (proxy *ProxyHelper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch r.URL.Path {
@@ -124,14 +184,26 @@ func (s *sseHandler) Send(eventType string, data string) {
 	s.m.Lock()
 	defer s.m.Unlock()
 	for _, ch := range s.requests {
-		ch := ch
-		go func(ch chan event) {
-			ch <- event{
-				Type: eventType,
-				Data: data,
-			}
-		}(ch)
+		select {
+		case ch <- event{Type: eventType, Data: data}:
+		default:
+			// A device that stopped reading, like a sleeping phone, misses the
+			// event instead of holding back the others
+		}
+	}
+}
+
+// hold sends e now and to later connections until replaced. It reports whether
+// an event was held before.
+func (s *sseHandler) hold(e *event) bool {
+	s.m.Lock()
+	wasHeld := s.held != nil
+	s.held = e
+	s.m.Unlock()
+	if e != nil {
+		s.Send(e.Type, e.Data)
 	}
+	return wasHeld
 }
 
 func (s *sseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -143,16 +215,22 @@ func (s *sseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	id := atomic.AddInt64(&s.counter, 1)
 	s.m.Lock()
-	events := make(chan event)
+	events := make(chan event, sseBufferSize)
 	s.requests[id] = events
+	held := s.held
 	s.m.Unlock()
 	defer func() {
 		s.m.Lock()
 		defer s.m.Unlock()
 		delete(s.requests, id)
-		close(events)
 	}()
 
+	if held != nil {
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", held.Type, held.Data); err != nil {
+			return
+		}
+	}
+
 	timer := time.NewTimer(0)
 loop:
 	for {
@@ -191,6 +269,18 @@ func (proxy *ProxyHelper) SendSSE(eventType, data string) {
 	proxy.Sse.Send(eventType, data)
 }
 
+// SendFragments tells the browser which routes changed so it can morph them in
+// place instead of reloading. Route paths use chi patterns like /users/{id}.
+func (proxy *ProxyHelper) SendFragments(routes []string) {
+	data, err := json.Marshal(routes)
+	if err != nil {
+		log.Printf("failed to encode changed routes: %v\n", err)
+		proxy.Sse.Send("message", "reload")
+		return
+	}
+	proxy.Sse.Send("fragments", string(data))
+}
+
 // RoundTripper with retry and exponential backoff
 func (rt *roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
 	var bodyBytes []byte
@@ -212,7 +302,12 @@ func (rt *roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
 		}
 		resp, err = http.DefaultTransport.RoundTrip(req)
 		if err != nil {
-			time.Sleep(rt.initialDelay * time.Duration(math.Pow(rt.backoffExponent, float64(retries))))
+			// The browser left or the proxy is shutting down
+			select {
+			case <-r.Context().Done():
+				return nil, r.Context().Err()
+			case <-time.After(rt.initialDelay * time.Duration(math.Pow(rt.backoffExponent, float64(retries)))):
+			}
 			continue
 		}
 		rt.setShouldSkipResponseModificationHeader(r, resp)
@@ -1,10 +1,241 @@
 (function() {
-    let gothicframework_reloadSrc = window.gothicframework_reloadSrc || new EventSource("/_gothicframework/reload/events");
+    if (window.gothicframework_reloadSrc) {
+      return;
+    }
+    let gothicframework_reloadSrc = new EventSource("/_gothicframework/reload/events");
     gothicframework_reloadSrc.onmessage = (event) => {
       if (event && event.data === "reload") {
         window.location.reload();
       }
     };
+    // Swap the changed stylesheet for a fresh copy, keeping the old one until
+    // the new one loads so the page never renders unstyled
+    gothicframework_reloadSrc.addEventListener("css", (event) => {
+      document.querySelectorAll('link[rel="stylesheet"]').forEach((link) => {
+        const url = new URL(link.href, window.location.href);
+        if (url.origin !== window.location.origin || url.pathname !== event.data) {
+          return;
+        }
+        url.searchParams.set("gothicframework_v", Date.now());
+        const next = link.cloneNode();
+        next.href = url.toString();
+        next.onload = () => link.remove();
+        next.onerror = () => next.remove();
+        link.after(next);
+      });
+    });
+
+    // Elements filled by HTMX GET requests, with the request that filled them.
+    // Page morphs leave their content alone, it is refreshed from its own route.
+    const fragments = new Map();
+    document.addEventListener("htmx:afterSwap", (event) => {
+      const detail = event.detail;
+      if (!detail.requestConfig || detail.requestConfig.verb !== "get" || detail.boosted) {
+        return;
+      }
+      const source = detail.requestConfig.elt || detail.target;
+      const swapAttribute = source.closest && source.closest("[hx-swap]");
+      const swap = swapAttribute ? swapAttribute.getAttribute("hx-swap").split(" ")[0] : window.htmx.config.defaultSwapStyle;
+      if (swap !== "innerHTML" && swap !== "outerHTML") {
+        return;
+      }
+      fragments.set(event.target, {
+        path: detail.pathInfo.requestPath,
+        outer: event.target !== detail.target,
+      });
+    });
+
+    // Routes come as chi patterns, /users/{id} matches /users/42
+    const matchesRoute = (routes, path) => {
+      const pathname = new URL(path, window.location.href).pathname;
+      return routes.some((route) => {
+        const pattern = route.replace(/[.*+?^$()|[\]\\]/g, "\\$&").replace(/\{[^}]+\}/g, "[^/]+");
+        return new RegExp("^" + pattern + "/?$").test(pathname);
+      });
+    };
+
+    const sameNode = (from, to) => from.nodeType === to.nodeType && from.nodeName === to.nodeName && (from.id || "") === (to.id || "");
+
+    // morph updates from to look like to, reusing nodes so focus, form input
+    // and scroll positions survive
+    const morph = (from, to) => {
+      if (!sameNode(from, to)) {
+        from.replaceWith(to);
+        return;
+      }
+      if (from.nodeType !== Node.ELEMENT_NODE) {
+        if (from.nodeValue !== to.nodeValue) {
+          from.nodeValue = to.nodeValue;
+        }
+        return;
+      }
+      for (const attribute of Array.from(from.attributes)) {
+        if (!to.hasAttribute(attribute.name)) {
+          from.removeAttribute(attribute.name);
+        }
+      }
+      for (const attribute of Array.from(to.attributes)) {
+        if (from.getAttribute(attribute.name) !== attribute.value) {
+          from.setAttribute(attribute.name, attribute.value);
+        }
+      }
+      if (!fragments.has(from)) {
+        morphChildren(from, to);
+      }
+    };
+
+    const morphChildren = (from, to) => {
+      let current = from.firstChild;
+      for (const node of Array.from(to.childNodes)) {
+        let match = null;
+        for (let candidate = current; candidate; candidate = candidate.nextSibling) {
+          if (sameNode(candidate, node)) {
+            match = candidate;
+            break;
+          }
+          // Only ids are searched ahead, other nodes must be next in line
+          if (!node.id) {
+            break;
+          }
+        }
+        if (match) {
+          if (match !== current) {
+            from.insertBefore(match, current);
+          }
+          morph(match, node);
+          current = match.nextSibling;
+        } else {
+          from.insertBefore(node, current);
+        }
+      }
+      while (current) {
+        const next = current.nextSibling;
+        current.remove();
+        current = next;
+      }
+    };
+
+    const refreshPage = async () => {
+      const response = await fetch(window.location.href, { headers: { "Accept": "text/html" } });
+      if (!response.ok) {
+        throw new Error(response.statusText);
+      }
+      const page = new DOMParser().parseFromString(await response.text(), "text/html");
+      document.title = page.title;
+      morph(document.body, page.body);
+    };
+
+    const refreshFragment = async (element, fragment) => {
+      const response = await fetch(fragment.path, {
+        headers: { "HX-Request": "true", "HX-Current-URL": window.location.href },
+      });
+      if (!response.ok) {
+        throw new Error(response.statusText);
+      }
+      const template = document.createElement("template");
+      template.innerHTML = await response.text();
+      fragments.delete(element);
+      if (!fragment.outer) {
+        morphChildren(element, template.content);
+        fragments.set(element, fragment);
+      } else if (template.content.children.length === 1) {
+        const next = template.content.firstElementChild;
+        morph(element, next);
+        const updated = element.isConnected ? element : next;
+        fragments.set(updated, fragment);
+      } else {
+        throw new Error("fragment " + fragment.path + " has several root elements");
+      }
+    };
+
+    gothicframework_reloadSrc.addEventListener("fragments", async (event) => {
+      const routes = JSON.parse(event.data);
+      try {
+        await refreshPage();
+        for (const [element, fragment] of Array.from(fragments)) {
+          if (!element.isConnected) {
+            fragments.delete(element);
+          } else if (matchesRoute(routes, fragment.path)) {
+            await refreshFragment(element, fragment);
+          }
+        }
+        if (window.htmx) {
+          window.htmx.process(document.body);
+        }
+      } catch (error) {
+        console.warn("gothicframework: falling back to a full reload,", error);
+        window.location.reload();
+      }
+    });
+
+    // Build errors are drawn with DOM and CSSOM calls only: the CSP blocks
+    // inline style attributes and style tags without the page nonce
+    const overlayId = "gothicframework-build-error";
+    const createElement = (tag, styles, text) => {
+      const element = document.createElement(tag);
+      Object.assign(element.style, styles);
+      if (text) {
+        element.textContent = text;
+      }
+      return element;
+    };
+    const removeOverlay = () => {
+      const overlay = document.getElementById(overlayId);
+      if (overlay) {
+        overlay.remove();
+      }
+    };
+    gothicframework_reloadSrc.addEventListener("build-error", (event) => {
+      const buildError = JSON.parse(event.data);
+      removeOverlay();
+      const overlay = createElement("div", {
+        position: "fixed",
+        inset: "0",
+        zIndex: "2147483647",
+        overflow: "auto",
+        padding: "32px",
+        background: "rgba(15, 15, 20, 0.95)",
+        color: "#f5f5f5",
+        font: "14px/1.5 ui-monospace, SFMono-Regular, Menlo, Consolas, monospace",
+      });
+      overlay.id = overlayId;
+      const close = createElement("button", {
+        float: "right",
+        background: "none",
+        border: "1px solid #666",
+        borderRadius: "4px",
+        color: "inherit",
+        cursor: "pointer",
+        padding: "4px 12px",
+      }, "Close");
+      close.addEventListener("click", removeOverlay);
+      overlay.appendChild(close);
+      overlay.appendChild(createElement("h2", { color: "#ff6b6b", margin: "0 0 24px", fontSize: "18px" }, "Build failed at " + buildError.stage));
+      for (const problem of buildError.problems) {
+        const item = createElement("div", { marginBottom: "16px", paddingLeft: "12px", borderLeft: "3px solid #ff6b6b" });
+        if (problem.file) {
+          let location = problem.file;
+          if (problem.line) {
+            location += ":" + problem.line + (problem.column ? ":" + problem.column : "");
+          }
+          item.appendChild(createElement("div", { color: "#8ab4f8" }, location));
+        }
+        item.appendChild(createElement("div", { whiteSpace: "pre-wrap" }, problem.message));
+        overlay.appendChild(item);
+      }
+      const output = createElement("pre", { whiteSpace: "pre-wrap", margin: "0" }, buildError.output);
+      if (buildError.problems.length === 0) {
+        overlay.appendChild(output);
+      } else {
+        // Full output, like the stack trace of a panic, stays one click away
+        const details = createElement("details", { marginTop: "24px", color: "#aaa" });
+        details.appendChild(createElement("summary", { cursor: "pointer" }, "Full output"));
+        details.appendChild(output);
+        overlay.appendChild(details);
+      }
+      document.body.appendChild(overlay);
+    });
+    gothicframework_reloadSrc.addEventListener("build-ok", removeOverlay);
     window.gothicframework_reloadSrc = gothicframework_reloadSrc;
     window.onbeforeunload = () => window.gothicframework_reloadSrc.close();
-  })();
\ No newline at end of file
+  })();
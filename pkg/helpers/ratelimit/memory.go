@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often full buckets are dropped from a MemoryStore.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens       float64
+	updated      time.Time
+	capacity     int
+	refillPerSec float64
+}
+
+// refill adds the tokens earned since the last update.
+func (bucket *bucket) refill(now time.Time) {
+	bucket.tokens += now.Sub(bucket.updated).Seconds() * bucket.refillPerSec
+	if bucket.tokens > float64(bucket.capacity) {
+		bucket.tokens = float64(bucket.capacity)
+	}
+	bucket.updated = now
+}
+
+// MemoryStore keeps buckets in the process memory. Each Lambda instance has
+// its own, so limits are per instance.
+type MemoryStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, lastSweep: time.Now()}
+}
+
+func (store *MemoryStore) Take(ctx context.Context, key string, capacity int, refillPerSec float64) (bool, time.Duration, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := time.Now()
+	store.sweep(now)
+
+	current, exists := store.buckets[key]
+	if !exists {
+		current = &bucket{tokens: float64(capacity), updated: now}
+		store.buckets[key] = current
+	}
+	current.capacity = capacity
+	current.refillPerSec = refillPerSec
+	current.refill(now)
+
+	if current.tokens >= 1 {
+		current.tokens--
+		return true, 0, nil
+	}
+	if refillPerSec <= 0 {
+		return false, time.Hour, nil
+	}
+	missing := 1 - current.tokens
+	return false, time.Duration(missing / refillPerSec * float64(time.Second)), nil
+}
+
+// sweep drops full buckets, which behave like missing ones, so idle clients
+// don't grow the map forever.
+func (store *MemoryStore) sweep(now time.Time) {
+	if now.Sub(store.lastSweep) < sweepInterval {
+		return
+	}
+	store.lastSweep = now
+	for key, current := range store.buckets {
+		current.refill(now)
+		if current.tokens >= float64(current.capacity) {
+			delete(store.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/felipegenef/gothicframework/pkg/helpers/config"
+	"github.com/felipegenef/gothicframework/pkg/helpers/sessions"
+)
+
+// Config is the "rateLimit" section of gothic-config.json.
+type Config struct {
+	// BehindCloudFront reads the client IP from the CloudFront-Viewer-Address
+	// header. Only set it when the server can't be reached without going
+	// through CloudFront, otherwise clients pick the IP they are limited by.
+	// The deploy command turns it on for the Lambda behind CloudFront, through
+	// the GOTHIC_BEHIND_CLOUDFRONT environment variable.
+	BehindCloudFront bool `json:"behindCloudFront"`
+}
+
+var DefaultConfig = Config{}
+
+var defaultConfig atomic.Pointer[Config]
+
+// LoadConfig reads the "rateLimit" section of gothic-config.json. It only
+// holds how clients are identified, limits are set per route.
+func LoadConfig(gothicConfig []byte) (Config, error) {
+	loaded, err := config.Section(gothicConfig, "rateLimit", DefaultConfig)
+	if err != nil {
+		return loaded, err
+	}
+	if os.Getenv("GOTHIC_BEHIND_CLOUDFRONT") == "true" {
+		loaded.BehindCloudFront = true
+	}
+	return loaded, nil
+}
+
+// SetDefault makes config the one used by ClientIP.
+func SetDefault(config Config) {
+	defaultConfig.Store(&config)
+}
+
+// Limit allows Requests per WindowInSec for each key, refilled continuously
+// like a token bucket, so short bursts up to Requests are accepted.
+type Limit struct {
+	Requests    int
+	WindowInSec int
+	// Key groups requests sharing a bucket. Defaults to ByIP.
+	Key func(r *http.Request) string
+	// Store holds the buckets. Defaults to the store set by SetDefaultStore,
+	// an in-memory store unless changed.
+	Store Store
+}
+
+// Store keeps token buckets. Use a shared store, e.g. backed by Redis, when
+// the app runs on several instances.
+type Store interface {
+	// Take removes a token from the bucket of key. When it is empty, it
+	// returns false and the time until the next token.
+	Take(ctx context.Context, key string, capacity int, refillPerSec float64) (bool, time.Duration, error)
+}
+
+var defaultStore atomic.Value
+
+func init() {
+	defaultConfig.Store(&DefaultConfig)
+	defaultStore.Store(storeHolder{NewMemoryStore()})
+}
+
+// storeHolder lets atomic.Value hold stores of different types.
+type storeHolder struct {
+	store Store
+}
+
+// SetDefaultStore replaces the store used by limits without a Store.
+func SetDefaultStore(store Store) {
+	defaultStore.Store(storeHolder{store})
+}
+
+// Handler rejects requests over the limit with 429 Too Many Requests and a
+// Retry-After header. Buckets are scoped by route, so name must identify it,
+// e.g. "GET /api/login". Store errors let requests through.
+func (limit *Limit) Handler(name string, next http.Handler) http.Handler {
+	key := limit.Key
+	if key == nil {
+		key = ByIP
+	}
+	refillPerSec := float64(limit.Requests) / math.Max(float64(limit.WindowInSec), 1)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		store := limit.Store
+		if store == nil {
+			store = defaultStore.Load().(storeHolder).store
+		}
+		allowed, retryAfter, err := store.Take(r.Context(), name+"|"+key(r), limit.Requests, refillPerSec)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "error checking rate limit", "route", name, "error", err)
+		}
+		if err == nil && !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			w.Header().Set("Cache-Control", "no-store")
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ByIP keys requests by client IP.
+func ByIP(r *http.Request) string {
+	return "ip:" + ClientIP(r)
+}
+
+// ByHeader keys requests by the value of a header, such as an API key,
+// falling back to the client IP when it is missing.
+func ByHeader(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if value := r.Header.Get(name); value != "" {
+			return "header:" + value
+		}
+		return ByIP(r)
+	}
+}
+
+// BySession keys requests by the user logged in with the sessions package,
+// falling back to the client IP for anonymous visitors.
+func BySession(r *http.Request) string {
+	if manager := sessions.Default(); manager != nil {
+		if session, err := manager.Get(r); err == nil && session.IsAuthenticated() {
+			return "user:" + session.UserID()
+		}
+	}
+	return ByIP(r)
+}
+
+// ClientIP returns the connection address of r, or the viewer IP sent by
+// CloudFront in the CloudFront-Viewer-Address header when BehindCloudFront is
+// set. X-Forwarded-For is ignored since clients can forge it.
+func ClientIP(r *http.Request) string {
+	if !defaultConfig.Load().BehindCloudFront {
+		return remoteIP(r)
+	}
+	if address := r.Header.Get("CloudFront-Viewer-Address"); address != "" {
+		// The port follows the last colon, IPv6 addresses are not bracketed
+		if index := strings.LastIndex(address, ":"); index > 0 {
+			return address[:index]
+		}
+		return address
+	}
+	return remoteIP(r)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBucketRefill(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		tokens       float64
+		capacity     int
+		refillPerSec float64
+		elapsed      time.Duration
+		want         float64
+	}{
+		{name: "no time elapsed", tokens: 0, capacity: 10, refillPerSec: 1, elapsed: 0, want: 0},
+		{name: "partial refill", tokens: 0, capacity: 10, refillPerSec: 2, elapsed: 1500 * time.Millisecond, want: 3},
+		{name: "fractional tokens", tokens: 0.5, capacity: 10, refillPerSec: 0.5, elapsed: time.Second, want: 1},
+		{name: "capped at capacity", tokens: 9, capacity: 10, refillPerSec: 1, elapsed: time.Hour, want: 10},
+		{name: "no refill", tokens: 1, capacity: 10, refillPerSec: 0, elapsed: time.Hour, want: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			current := &bucket{tokens: test.tokens, updated: start, capacity: test.capacity, refillPerSec: test.refillPerSec}
+			current.refill(start.Add(test.elapsed))
+			if current.tokens != test.want {
+				t.Errorf("tokens = %v, want %v", current.tokens, test.want)
+			}
+			if !current.updated.Equal(start.Add(test.elapsed)) {
+				t.Errorf("updated = %v, want %v", current.updated, start.Add(test.elapsed))
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if allowed, _, _ := store.Take(ctx, "key", 3, 1); !allowed {
+			t.Fatalf("request %d rejected within the burst", i+1)
+		}
+	}
+	allowed, retryAfter, err := store.Take(ctx, "key", 3, 1)
+	if err != nil || allowed {
+		t.Fatalf("Take() = %v, %v, want a rejection once the bucket is empty", allowed, err)
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("retryAfter = %v, want up to the one second a token takes", retryAfter)
+	}
+	if allowed, _, _ := store.Take(ctx, "other", 3, 1); !allowed {
+		t.Error("another key shared the bucket")
+	}
+
+	// Refill as if a second had passed
+	store.buckets["key"].updated = store.buckets["key"].updated.Add(-time.Second)
+	if allowed, _, _ := store.Take(ctx, "key", 3, 1); !allowed {
+		t.Error("the refilled token was not available")
+	}
+	if allowed, _, _ := store.Take(ctx, "key", 3, 1); allowed {
+		t.Error("more than one token was refilled in a second")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	limit := &Limit{Requests: 2, WindowInSec: 60, Store: NewMemoryStore()}
+	handler := limit.Handler("GET /api/items", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	wantStatus := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+	for i, want := range wantStatus {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+		if recorder.Code != want {
+			t.Errorf("request %d status = %d, want %d", i+1, recorder.Code, want)
+		}
+		if want == http.StatusTooManyRequests && recorder.Header().Get("Retry-After") != "30" {
+			t.Errorf("Retry-After = %q, want 30", recorder.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name             string
+		behindCloudFront bool
+		remoteAddr       string
+		viewerAddress    string
+		want             string
+	}{
+		{name: "connection address", remoteAddr: "203.0.113.7:51234", want: "203.0.113.7"},
+		{name: "header ignored by default", remoteAddr: "203.0.113.7:51234", viewerAddress: "198.51.100.1:443", want: "203.0.113.7"},
+		{name: "behind cloudfront", behindCloudFront: true, remoteAddr: "10.0.0.1:51234", viewerAddress: "198.51.100.1:443", want: "198.51.100.1"},
+		{name: "behind cloudfront ipv6", behindCloudFront: true, remoteAddr: "10.0.0.1:51234", viewerAddress: "2001:db8::1:443", want: "2001:db8::1"},
+		{name: "behind cloudfront without header", behindCloudFront: true, remoteAddr: "10.0.0.1:51234", want: "10.0.0.1"},
+		{name: "address without port", remoteAddr: "203.0.113.7", want: "203.0.113.7"},
+	}
+	t.Cleanup(func() { SetDefault(DefaultConfig) })
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			SetDefault(Config{BehindCloudFront: test.behindCloudFront})
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.RemoteAddr = test.remoteAddr
+			if test.viewerAddress != "" {
+				request.Header.Set("CloudFront-Viewer-Address", test.viewerAddress)
+			}
+			if got := ClientIP(request); got != test.want {
+				t.Errorf("ClientIP() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigBehindCloudFront(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  string
+		want bool
+	}{
+		{name: "default", file: `{}`, want: false},
+		{name: "config", file: `{"rateLimit": {"behindCloudFront": true}}`, want: true},
+		{name: "deployed behind cloudfront", file: `{"rateLimit": {"behindCloudFront": false}}`, env: "true", want: true},
+		{name: "other env value", file: `{}`, env: "1", want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("GOTHIC_BEHIND_CLOUDFRONT", test.env)
+			config, err := LoadConfig([]byte(test.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.BehindCloudFront != test.want {
+				t.Errorf("BehindCloudFront = %v, want %v", config.BehindCloudFront, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CachePolicy describes the Cache-Control header of a route. MaxAgeInSec
+// applies to browsers and SMaxAgeInSec to shared caches such as CloudFront,
+// so pages can live long at the edge while browsers keep revalidating.
+type CachePolicy struct {
+	MaxAgeInSec               int
+	SMaxAgeInSec              int
+	StaleWhileRevalidateInSec int
+	StaleIfErrorInSec         int
+	// Private keeps the response out of shared caches.
+	Private bool
+	// NoStore disables caching entirely and ignores every other field.
+	NoStore bool
+	// NoCache lets caches keep the response but makes them revalidate it on
+	// every use, answered with 304 when its ETag still matches. Every other
+	// field but Private is ignored.
+	NoCache bool
+}
+
+// NoStorePolicy is used for every non-GET route, since mutations must never be
+// served from a cache.
+var NoStorePolicy = CachePolicy{NoStore: true}
+
+// DefaultDynamicPolicy is used by DYNAMIC routes without a CachePolicy.
+var DefaultDynamicPolicy = CachePolicy{Private: true, NoStore: true}
+
+// DefaultDynamicETagPolicy is used by DYNAMIC routes with ETag set and without
+// a CachePolicy. A stored page is what browsers revalidate with If-None-Match.
+var DefaultDynamicETagPolicy = CachePolicy{Private: true, NoCache: true}
+
+// defaultStaleIfErrorInSec lets the edge keep serving a STATIC or ISR page for
+// a day while the server is failing.
+const defaultStaleIfErrorInSec = 86400
+
+// String renders the policy as a Cache-Control header value.
+func (policy CachePolicy) String() string {
+	if policy.NoStore {
+		if policy.Private {
+			return "private, no-store"
+		}
+		return "no-store"
+	}
+	if policy.NoCache {
+		if policy.Private {
+			return "private, no-cache"
+		}
+		return "no-cache"
+	}
+
+	directives := []string{"public"}
+	if policy.Private {
+		directives = []string{"private"}
+	}
+	directives = append(directives, fmt.Sprintf("max-age=%d", policy.MaxAgeInSec))
+	if policy.SMaxAgeInSec > 0 && !policy.Private {
+		directives = append(directives, fmt.Sprintf("s-maxage=%d", policy.SMaxAgeInSec))
+	}
+	if policy.StaleWhileRevalidateInSec > 0 {
+		directives = append(directives, fmt.Sprintf("stale-while-revalidate=%d", policy.StaleWhileRevalidateInSec))
+	}
+	if policy.StaleIfErrorInSec > 0 {
+		directives = append(directives, fmt.Sprintf("stale-if-error=%d", policy.StaleIfErrorInSec))
+	}
+	return strings.Join(directives, ", ")
+}
+
+type sharedRenderKey struct{}
+
+// IsSharedRender reports whether ctx renders a STATIC or ISR page. Their
+// markup is cached by CloudFront and served to every visitor, so it must not
+// hold per-visitor data such as CSRF tokens or CSP nonces.
+func IsSharedRender(ctx context.Context) bool {
+	shared, _ := ctx.Value(sharedRenderKey{}).(bool)
+	return shared
+}
+
+// SharedPolicyWriter is implemented by response writers sending a
+// Content-Security-Policy, such as the one of the csp middleware. Shared
+// renders carry no nonce, so UseSharedPolicy is called before they are
+// written, to send a policy that doesn't rely on one. It never depends on the
+// markup: a script injected into the page must not be allowed by it.
+type SharedPolicyWriter interface {
+	UseSharedPolicy()
+}
+
+// useSharedPolicy finds the SharedPolicyWriter among the writers wrapping w.
+// Middlewares between it and the route must implement Unwrap.
+func useSharedPolicy(w http.ResponseWriter) {
+	for {
+		if policy, ok := w.(SharedPolicyWriter); ok {
+			policy.UseSharedPolicy()
+			return
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = unwrapper.Unwrap()
+	}
+}
+
+// isCacheable reports whether responses of this route may be stored by the
+// local cache, browsers or CloudFront. Only GET requests are cached.
+func (config *RouteConfig[T]) isCacheable() bool {
+	return config.Type != DYNAMIC && config.HttpMethod == GET
+}
+
+// getCachePolicy returns the route CachePolicy, falling back to a default for
+// its render type. Browsers always revalidate STATIC and ISR pages, while the
+// edge keeps STATIC pages until the next deploy invalidates them.
+func (config *RouteConfig[T]) getCachePolicy() CachePolicy {
+	if config.HttpMethod != GET {
+		return NoStorePolicy
+	}
+	if config.CachePolicy != nil {
+		return *config.CachePolicy
+	}
+
+	switch config.Type {
+	case STATIC:
+		return CachePolicy{
+			SMaxAgeInSec:      31536000,
+			StaleIfErrorInSec: defaultStaleIfErrorInSec,
+		}
+	case ISR:
+		return CachePolicy{
+			SMaxAgeInSec:              config.RevalidateInSec,
+			StaleWhileRevalidateInSec: config.RevalidateInSec,
+			StaleIfErrorInSec:         defaultStaleIfErrorInSec,
+		}
+	default:
+		if config.usesETag() {
+			return DefaultDynamicETagPolicy
+		}
+		return DefaultDynamicPolicy
+	}
+}
+
+// isNotModified evaluates the conditional headers of r. If-None-Match takes
+// precedence over If-Modified-Since, as required by RFC 9110.
+func isNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			// Weak comparison: W/ prefixes are ignored
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+		// HTTP dates have a one second precision
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
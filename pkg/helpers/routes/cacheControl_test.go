@@ -0,0 +1,58 @@
+package helpers
+
+import (
+	"testing"
+)
+
+func TestCachePolicyString(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy CachePolicy
+		want   string
+	}{
+		{name: "zero", policy: CachePolicy{}, want: "public, max-age=0"},
+		{name: "no-store", policy: NoStorePolicy, want: "no-store"},
+		{name: "private no-store", policy: DefaultDynamicPolicy, want: "private, no-store"},
+		{name: "no-store ignores the rest", policy: CachePolicy{NoStore: true, NoCache: true, MaxAgeInSec: 60, SMaxAgeInSec: 600}, want: "no-store"},
+		{name: "no-cache", policy: CachePolicy{NoCache: true}, want: "no-cache"},
+		{name: "private no-cache", policy: DefaultDynamicETagPolicy, want: "private, no-cache"},
+		{name: "no-cache ignores max-age", policy: CachePolicy{NoCache: true, MaxAgeInSec: 60, SMaxAgeInSec: 600}, want: "no-cache"},
+		{name: "browser and edge", policy: CachePolicy{MaxAgeInSec: 60, SMaxAgeInSec: 600}, want: "public, max-age=60, s-maxage=600"},
+		{name: "private drops s-maxage", policy: CachePolicy{Private: true, MaxAgeInSec: 60, SMaxAgeInSec: 600}, want: "private, max-age=60"},
+		{name: "stale directives", policy: CachePolicy{SMaxAgeInSec: 60, StaleWhileRevalidateInSec: 30, StaleIfErrorInSec: 86400}, want: "public, max-age=0, s-maxage=60, stale-while-revalidate=30, stale-if-error=86400"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.policy.String(); got != test.want {
+				t.Errorf("String() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGetCachePolicy(t *testing.T) {
+	override := &CachePolicy{MaxAgeInSec: 300}
+	tests := []struct {
+		name   string
+		config RouteConfig[any]
+		want   CachePolicy
+	}{
+		{name: "static", config: RouteConfig[any]{Type: STATIC}, want: CachePolicy{SMaxAgeInSec: 31536000, StaleIfErrorInSec: defaultStaleIfErrorInSec}},
+		{name: "isr", config: RouteConfig[any]{Type: ISR, RevalidateInSec: 60}, want: CachePolicy{SMaxAgeInSec: 60, StaleWhileRevalidateInSec: 60, StaleIfErrorInSec: defaultStaleIfErrorInSec}},
+		{name: "dynamic", config: RouteConfig[any]{Type: DYNAMIC}, want: DefaultDynamicPolicy},
+		{name: "dynamic with etag", config: RouteConfig[any]{Type: DYNAMIC, ETag: true}, want: DefaultDynamicETagPolicy},
+		{name: "dynamic with etag override", config: RouteConfig[any]{Type: DYNAMIC, ETag: true, CachePolicy: override}, want: *override},
+		{name: "post with etag", config: RouteConfig[any]{Type: DYNAMIC, HttpMethod: POST, ETag: true}, want: NoStorePolicy},
+		{name: "static override", config: RouteConfig[any]{Type: STATIC, CachePolicy: override}, want: *override},
+		{name: "dynamic override", config: RouteConfig[any]{Type: DYNAMIC, CachePolicy: override}, want: *override},
+		{name: "post", config: RouteConfig[any]{Type: DYNAMIC, HttpMethod: POST}, want: NoStorePolicy},
+		{name: "post ignores the override", config: RouteConfig[any]{Type: STATIC, HttpMethod: POST, CachePolicy: override}, want: NoStorePolicy},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.config.getCachePolicy(); got != test.want {
+				t.Errorf("getCachePolicy() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
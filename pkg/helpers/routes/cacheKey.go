@@ -0,0 +1,94 @@
+package helpers
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DefaultIgnoredQueryParams are the tracking parameters left out of the cache
+// key when CacheKey.IgnoredQueryParams is nil. They never change the rendered
+// page, so keeping them would only create duplicate cache entries.
+var DefaultIgnoredQueryParams = []string{
+	"utm_source",
+	"utm_medium",
+	"utm_campaign",
+	"utm_term",
+	"utm_content",
+	"utm_id",
+	"fbclid",
+	"gclid",
+	"msclkid",
+}
+
+// CacheKey declares which parts of a request identify a cached STATIC or ISR
+// response. The same settings are used by the local cache, the Vary header and
+// the CloudFront cache policy generated on deploy.
+type CacheKey struct {
+	// QueryParams is the allowlist of query parameters in the key. When nil,
+	// every parameter except IgnoredQueryParams is used; an empty, non-nil
+	// slice keeps query parameters out of the key entirely.
+	QueryParams []string
+	// IgnoredQueryParams is only used when QueryParams is nil. Defaults to
+	// DefaultIgnoredQueryParams.
+	IgnoredQueryParams []string
+	// Headers and Cookies are added to the key and emitted in the Vary header.
+	Headers []string
+	Cookies []string
+}
+
+// Key builds the normalized cache key for r. Query parameters are sorted so
+// their order in the URL does not matter.
+func (key CacheKey) Key(r *http.Request) string {
+	var builder strings.Builder
+	builder.WriteString(r.URL.Path)
+
+	query := url.Values{}
+	for name, values := range r.URL.Query() {
+		if key.includesQueryParam(name) {
+			query[name] = values
+		}
+	}
+	if len(query) > 0 {
+		builder.WriteString("?")
+		// Encode sorts by parameter name
+		builder.WriteString(query.Encode())
+	}
+
+	for _, name := range key.Headers {
+		builder.WriteString("|header:" + strings.ToLower(name) + "=" + r.Header.Get(name))
+	}
+	for _, name := range key.Cookies {
+		value := ""
+		if cookie, err := r.Cookie(name); err == nil {
+			value = cookie.Value
+		}
+		builder.WriteString("|cookie:" + name + "=" + value)
+	}
+	return builder.String()
+}
+
+// SetVary announces the headers and cookies the response depends on.
+func (key CacheKey) SetVary(w http.ResponseWriter) {
+	for _, name := range key.Headers {
+		w.Header().Add("Vary", name)
+	}
+	if len(key.Cookies) > 0 {
+		w.Header().Add("Vary", "Cookie")
+	}
+}
+
+func (key CacheKey) ignoredQueryParams() []string {
+	if key.IgnoredQueryParams == nil {
+		return DefaultIgnoredQueryParams
+	}
+	return key.IgnoredQueryParams
+}
+
+func (key CacheKey) includesQueryParam(name string) bool {
+	if key.QueryParams != nil {
+		return slices.Contains(key.QueryParams, name)
+	}
+	return !slices.Contains(key.ignoredQueryParams(), name)
+}
@@ -0,0 +1,234 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	helpers "github.com/felipegenef/gothicframework/pkg/helpers"
+)
+
+func TestCacheKeyKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     CacheKey
+		url     string
+		headers map[string]string
+		cookies map[string]string
+		want    string
+	}{
+		{name: "path only", url: "/blog", want: "/blog"},
+		{name: "sorted query", url: "/blog?b=2&a=1", want: "/blog?a=1&b=2"},
+		{name: "tracking params ignored", url: "/blog?utm_source=mail&page=2&gclid=x", want: "/blog?page=2"},
+		{name: "only tracking params", url: "/blog?utm_source=mail", want: "/blog"},
+		{name: "allowlist", key: CacheKey{QueryParams: []string{"page"}}, url: "/blog?page=2&sort=asc", want: "/blog?page=2"},
+		{name: "empty allowlist", key: CacheKey{QueryParams: []string{}}, url: "/blog?page=2", want: "/blog"},
+		{name: "custom ignored params", key: CacheKey{IgnoredQueryParams: []string{"ref"}}, url: "/blog?ref=x&utm_source=mail", want: "/blog?utm_source=mail"},
+		{name: "repeated param", url: "/blog?tag=b&tag=a", want: "/blog?tag=b&tag=a"},
+		{name: "header", key: CacheKey{Headers: []string{"Accept-Language"}}, url: "/blog", headers: map[string]string{"Accept-Language": "fr"}, want: "/blog|header:accept-language=fr"},
+		{name: "missing header", key: CacheKey{Headers: []string{"Accept-Language"}}, url: "/blog", want: "/blog|header:accept-language="},
+		{name: "cookie", key: CacheKey{Cookies: []string{"theme"}}, url: "/blog?page=2", cookies: map[string]string{"theme": "dark", "other": "x"}, want: "/blog?page=2|cookie:theme=dark"},
+		{name: "missing cookie", key: CacheKey{Cookies: []string{"theme"}}, url: "/blog", want: "/blog|cookie:theme="},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+			for name, value := range test.headers {
+				request.Header.Set(name, value)
+			}
+			for name, value := range test.cookies {
+				request.AddCookie(&http.Cookie{Name: name, Value: value})
+			}
+			if got := test.key.Key(request); got != test.want {
+				t.Errorf("Key() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCacheKeySetVary(t *testing.T) {
+	tests := []struct {
+		name string
+		key  CacheKey
+		want []string
+	}{
+		{name: "no headers or cookies", key: CacheKey{QueryParams: []string{"page"}}},
+		{name: "headers", key: CacheKey{Headers: []string{"Accept-Language", "CloudFront-Viewer-Country"}}, want: []string{"Accept-Language", "CloudFront-Viewer-Country"}},
+		{name: "cookies", key: CacheKey{Cookies: []string{"theme", "currency"}}, want: []string{"Cookie"}},
+		{name: "headers and cookies", key: CacheKey{Headers: []string{"Accept-Language"}, Cookies: []string{"theme"}}, want: []string{"Accept-Language", "Cookie"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			test.key.SetVary(recorder)
+			if got := recorder.Header().Values("Vary"); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Vary = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseCacheKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    CacheKey
+		wantErr bool
+	}{
+		{
+			name: "every field",
+			source: `var BlogConfig = routes.RouteConfig[Props]{
+	Type: routes.ISR,
+	CacheKey: routes.CacheKey{
+		QueryParams:        []string{"page", "sort"},
+		IgnoredQueryParams: []string{"ref"},
+		Headers:            []string{"Accept-Language"},
+		Cookies:            []string{"theme"},
+	},
+}`,
+			want: CacheKey{QueryParams: []string{"page", "sort"}, IgnoredQueryParams: []string{"ref"}, Headers: []string{"Accept-Language"}, Cookies: []string{"theme"}},
+		},
+		{
+			name:   "empty allowlist",
+			source: `var BlogConfig = routes.RouteConfig[Props]{CacheKey: routes.CacheKey{QueryParams: []string{}}}`,
+			want:   CacheKey{QueryParams: []string{}},
+		},
+		{
+			name:   "explicit nil",
+			source: `var BlogConfig = routes.RouteConfig[Props]{CacheKey: routes.CacheKey{QueryParams: nil, Headers: []string{"Accept-Language"}}}`,
+			want:   CacheKey{Headers: []string{"Accept-Language"}},
+		},
+		{
+			name:   "raw string",
+			source: "var BlogConfig = routes.RouteConfig[Props]{CacheKey: routes.CacheKey{Cookies: []string{`theme`}}}",
+			want:   CacheKey{Cookies: []string{"theme"}},
+		},
+		{
+			name:   "no cache key",
+			source: `var BlogConfig = routes.RouteConfig[Props]{Type: routes.STATIC}`,
+		},
+		{
+			name:   "no route config",
+			source: `func Blog() {}`,
+		},
+		{
+			name: "commented out",
+			source: `var BlogConfig = routes.RouteConfig[Props]{
+	Type: routes.ISR,
+	// CacheKey: routes.CacheKey{Headers: []string{"Accept-Language"}},
+}`,
+		},
+		{
+			name: "in a string",
+			source: `var BlogConfig = routes.RouteConfig[Props]{Type: routes.ISR}
+
+var example = "CacheKey: routes.CacheKey{Headers: []string{\"Accept-Language\"}}"`,
+		},
+		{
+			name: "other config",
+			source: `var OtherConfig = routes.RouteConfig[Props]{CacheKey: routes.CacheKey{Headers: []string{"Accept-Language"}}}
+
+var BlogConfig = routes.RouteConfig[Props]{Type: routes.ISR}`,
+		},
+		{
+			name: "grouped declaration",
+			source: `var (
+	title      = "Blog"
+	BlogConfig = routes.RouteConfig[Props]{CacheKey: routes.CacheKey{Cookies: []string{"theme"}}}
+)`,
+			want: CacheKey{Cookies: []string{"theme"}},
+		},
+		{
+			name:    "variable",
+			source:  `var BlogConfig = routes.RouteConfig[Props]{CacheKey: routes.CacheKey{Headers: headers}}`,
+			wantErr: true,
+		},
+		{
+			name:    "constant",
+			source:  `var BlogConfig = routes.RouteConfig[Props]{CacheKey: routes.CacheKey{Headers: []string{languageHeader}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "concatenation",
+			source:  `var BlogConfig = routes.RouteConfig[Props]{CacheKey: routes.CacheKey{Cookies: []string{"the" + "me"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid go",
+			source:  `var BlogConfig = routes.RouteConfig[Props]{`,
+			wantErr: true,
+		},
+	}
+	helper := NewFileBasedRouteHelper()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := helper.parseCacheKey([]byte("package pages\n\n"+test.source+"\n"), "BlogConfig")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseCacheKey() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseCacheKey() = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergedCachePolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []CacheKey
+		want helpers.CachePolicyTemplateInfo
+	}{
+		{
+			name: "no routes",
+			want: helpers.CachePolicyTemplateInfo{QueryStringBehavior: "none"},
+		},
+		{
+			name: "default key",
+			keys: []CacheKey{{}},
+			want: helpers.CachePolicyTemplateInfo{QueryStringBehavior: "allExcept", QueryStrings: []string{"fbclid", "gclid", "msclkid", "utm_campaign", "utm_content", "utm_id", "utm_medium", "utm_source", "utm_term"}},
+		},
+		{
+			name: "allowlists",
+			keys: []CacheKey{{QueryParams: []string{"page"}}, {QueryParams: []string{"sort", "page"}}},
+			want: helpers.CachePolicyTemplateInfo{QueryStringBehavior: "whitelist", QueryStrings: []string{"page", "sort"}},
+		},
+		{
+			name: "no query params",
+			keys: []CacheKey{{QueryParams: []string{}}},
+			want: helpers.CachePolicyTemplateInfo{QueryStringBehavior: "none"},
+		},
+		{
+			name: "params ignored by every route",
+			keys: []CacheKey{{IgnoredQueryParams: []string{"ref", "utm_source"}}, {IgnoredQueryParams: []string{"utm_source"}}},
+			want: helpers.CachePolicyTemplateInfo{QueryStringBehavior: "allExcept", QueryStrings: []string{"utm_source"}},
+		},
+		{
+			name: "ignored param allowed by another route",
+			keys: []CacheKey{{IgnoredQueryParams: []string{"ref", "utm_source"}}, {QueryParams: []string{"ref"}}},
+			want: helpers.CachePolicyTemplateInfo{QueryStringBehavior: "allExcept", QueryStrings: []string{"utm_source"}},
+		},
+		{
+			name: "nothing ignored",
+			keys: []CacheKey{{IgnoredQueryParams: []string{}}, {}},
+			want: helpers.CachePolicyTemplateInfo{QueryStringBehavior: "all"},
+		},
+		{
+			name: "headers and cookies",
+			keys: []CacheKey{{QueryParams: []string{}, Headers: []string{"X-Country", "Accept-Language"}, Cookies: []string{"theme"}}, {QueryParams: []string{}, Headers: []string{"Accept-Language"}, Cookies: []string{"currency"}}},
+			want: helpers.CachePolicyTemplateInfo{QueryStringBehavior: "none", Headers: []string{"Accept-Language", "X-Country"}, Cookies: []string{"currency", "theme"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			helper := NewFileBasedRouteHelper()
+			for _, key := range test.keys {
+				helper.TemplateInfo.Routes = append(helper.TemplateInfo.Routes, RouteTemplate{CacheKey: key})
+			}
+			if got := helper.CachePolicy(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("CachePolicy() = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package helpers
+
+// Page identity
+//
+// A rendered page is identified by the SHA-256 of its markup, and nothing
+// else: not the props, the route or the deploy. Both the ETag and the 304
+// answers below rely only on that hash.
+//
+// STATIC and ISR pages are shared by every visitor and render without a CSP
+// nonce, so the hash covers the exact bytes sent and the ETag is strong.
+// DYNAMIC pages with ETag set carry the per-request nonce: it is left out of
+// the hash, and since two such responses are equivalent but not byte for byte
+// identical, their ETag is weak (W/"..."). A 304 makes the browser keep its
+// copy together with the policy holding the nonce it was rendered with, which
+// is why the csp middleware doesn't send the policy header on 304 responses.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a-h/templ"
+)
+
+// usesETag reports whether rendered pages are hashed. STATIC and ISR GET
+// routes always are, DYNAMIC GET routes only when ETag is set on the config.
+func (config *RouteConfig[T]) usesETag() bool {
+	if config.HttpMethod != GET {
+		return false
+	}
+	return config.isCacheable() || config.ETag
+}
+
+// renderWithETag buffers the rendered component to compute its ETag and
+// answers 304 Not Modified when the client already holds the same markup.
+func (config *RouteConfig[T]) renderWithETag(r *http.Request, w http.ResponseWriter, component templ.Component, lastModified time.Time) {
+	var body bytes.Buffer
+	if err := component.Render(r.Context(), &body); err != nil {
+		log.Printf("error rendering %s: %v", r.URL.Path, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if IsSharedRender(r.Context()) {
+		useSharedPolicy(w)
+	}
+	etag := contentETag(body.Bytes(), templ.GetNonce(r.Context()))
+	w.Header().Set("ETag", etag)
+	if isNotModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(body.Len()))
+	w.Write(body.Bytes())
+}
+
+// contentETag hashes body as described in the page identity comment above.
+func contentETag(body []byte, nonce string) string {
+	if nonce == "" {
+		sum := sha256.Sum256(body)
+		return `"` + hex.EncodeToString(sum[:16]) + `"`
+	}
+	sum := sha256.Sum256(bytes.ReplaceAll(body, []byte(nonce), nil))
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
@@ -0,0 +1,87 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContentETag(t *testing.T) {
+	page := []byte(`<html><script nonce="abc123">run()</script></html>`)
+	renewed := []byte(`<html><script nonce="xyz789">run()</script></html>`)
+	tests := []struct {
+		name       string
+		body       []byte
+		nonce      string
+		other      []byte
+		otherNonce string
+		wantSame   bool
+		wantWeak   bool
+	}{
+		{name: "same markup", body: page, other: page, wantSame: true},
+		{name: "other markup", body: page, other: []byte(`<html></html>`), wantSame: false},
+		{name: "other nonce", body: page, nonce: "abc123", other: renewed, otherNonce: "xyz789", wantSame: true, wantWeak: true},
+		{name: "other markup with a nonce", body: page, nonce: "abc123", other: []byte(`<html><script nonce="xyz789">stop()</script></html>`), otherNonce: "xyz789", wantSame: false, wantWeak: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			etag := contentETag(test.body, test.nonce)
+			if weak := strings.HasPrefix(etag, "W/"); weak != test.wantWeak {
+				t.Errorf("contentETag() = %s, want weak %v", etag, test.wantWeak)
+			}
+			if !strings.HasSuffix(etag, `"`) || !strings.HasPrefix(strings.TrimPrefix(etag, "W/"), `"`) {
+				t.Errorf("contentETag() = %s is not quoted", etag)
+			}
+			if same := etag == contentETag(test.other, test.otherNonce); same != test.wantSame {
+				t.Errorf("same ETag = %v, want %v", same, test.wantSame)
+			}
+		})
+	}
+}
+
+func TestIsNotModified(t *testing.T) {
+	lastModified := time.Date(2026, 1, 1, 12, 0, 0, 500, time.UTC)
+	etag := `"abc"`
+	tests := []struct {
+		name            string
+		method          string
+		ifNoneMatch     string
+		ifModifiedSince string
+		etag            string
+		want            bool
+	}{
+		{name: "no conditional headers", etag: etag, want: false},
+		{name: "matching etag", ifNoneMatch: `"abc"`, etag: etag, want: true},
+		{name: "other etag", ifNoneMatch: `"def"`, etag: etag, want: false},
+		{name: "etag in a list", ifNoneMatch: `"def", "abc"`, etag: etag, want: true},
+		{name: "weak candidate", ifNoneMatch: `W/"abc"`, etag: etag, want: true},
+		{name: "weak etag", ifNoneMatch: `"abc"`, etag: `W/"abc"`, want: true},
+		{name: "any etag", ifNoneMatch: "*", etag: etag, want: true},
+		{name: "head request", method: http.MethodHead, ifNoneMatch: `"abc"`, etag: etag, want: true},
+		{name: "post request", method: http.MethodPost, ifNoneMatch: `"abc"`, etag: etag, want: false},
+		{name: "not modified since", ifModifiedSince: lastModified.Format(http.TimeFormat), etag: etag, want: true},
+		{name: "modified since", ifModifiedSince: lastModified.Add(-time.Second).Format(http.TimeFormat), etag: etag, want: false},
+		{name: "invalid date", ifModifiedSince: "yesterday", etag: etag, want: false},
+		{name: "etag takes precedence", ifNoneMatch: `"def"`, ifModifiedSince: lastModified.Format(http.TimeFormat), etag: etag, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			method := test.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			request := httptest.NewRequest(method, "/", nil)
+			if test.ifNoneMatch != "" {
+				request.Header.Set("If-None-Match", test.ifNoneMatch)
+			}
+			if test.ifModifiedSince != "" {
+				request.Header.Set("If-Modified-Since", test.ifModifiedSince)
+			}
+			if got := isNotModified(request, test.etag, lastModified); got != test.want {
+				t.Errorf("isNotModified() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -1,18 +1,27 @@
 package helpers
 
 import (
+	"context"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/a-h/templ"
 	helpers "github.com/felipegenef/gothicframework/pkg/helpers"
+	"github.com/felipegenef/gothicframework/pkg/helpers/cors"
+	"github.com/felipegenef/gothicframework/pkg/helpers/metrics"
+	"github.com/felipegenef/gothicframework/pkg/helpers/ratelimit"
 	"github.com/go-chi/chi/v5"
 	"github.com/joho/godotenv"
 )
@@ -25,6 +34,19 @@ const (
 	DYNAMIC
 )
 
+func (configType ConfigType) String() string {
+	switch configType {
+	case ISR:
+		return "ISR"
+	case STATIC:
+		return "STATIC"
+	case DYNAMIC:
+		return "DYNAMIC"
+	default:
+		return "UNKNOWN"
+	}
+}
+
 type HttpMethod int
 
 const (
@@ -35,11 +57,35 @@ const (
 	DELETE
 )
 
+// String returns the net/http method name, e.g. "GET".
+func (method HttpMethod) String() string {
+	switch method {
+	case POST:
+		return http.MethodPost
+	case PUT:
+		return http.MethodPut
+	case PATCH:
+		return http.MethodPatch
+	case DELETE:
+		return http.MethodDelete
+	default:
+		return http.MethodGet
+	}
+}
+
 type RouteConfig[T any] struct {
 	Type            ConfigType
 	HttpMethod      HttpMethod
 	RevalidateInSec int
-	Middleware      func(w http.ResponseWriter, r *http.Request) T
+	CacheKey        CacheKey
+	CachePolicy     *CachePolicy
+	// ETag answers conditional requests on DYNAMIC GET routes too, STATIC and
+	// ISR ones always do. Without a CachePolicy such routes default to
+	// DefaultDynamicETagPolicy. See the page identity comment in etag.go.
+	ETag bool
+	// RateLimit answers 429 once a client exceeds it, before Middleware runs.
+	RateLimit  *ratelimit.Limit
+	Middleware func(w http.ResponseWriter, r *http.Request) T
 }
 
 var DefaultConfig = RouteConfig[any]{
@@ -54,242 +100,177 @@ var DefaultApiConfig = ApiRouteConfig{
 	HttpMethod: GET,
 }
 
-type isrLocalCache struct {
-	data       any
-	revalidate time.Time
+type localCache struct {
+	data        any
+	route       string
+	generatedAt time.Time
+	revalidate  time.Time
 }
 
 var localCacheMutex sync.RWMutex
-var localCacheValue map[string]any = make(map[string]any)
+var localCacheValue map[string]localCache = make(map[string]localCache)
+
+// localCacheRuns holds, per cache key, a channel closed once the Middleware
+// run generating its props ends. Guarded by localCacheMutex.
+var localCacheRuns = map[string]chan struct{}{}
+
+// serverStartTime is the Last-Modified date of STATIC pages outside local
+// serve: their content only changes with a new deployment.
+var serverStartTime = time.Now()
 
 func (config *RouteConfig[T]) RegisterRoute(r chi.Router, httpPath string, component func(T) templ.Component) {
 	godotenv.Load()
 	var localServe = os.Getenv("LOCAL_SERVE")
 	var isLocal = len(localServe) > 0 && localServe == "true"
-	if config.Type == STATIC {
-		switch config.HttpMethod {
-		case GET:
-			r.Get(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				if !isLocal {
-					w.Header().Set("Cache-Control", "max-age=31536000")
-					config.Render(r, w, component(config.Middleware(w, r)))
-					return
-				}
-				fullURL := r.URL.RequestURI() // this includes query parameters
-				config.Render(r, w, component(config.getCachedOrUpdate(fullURL, w, r)))
-			})
-		case POST:
-			r.Post(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				if !isLocal {
-					w.Header().Set("Cache-Control", "max-age=31536000")
-					config.Render(r, w, component(config.Middleware(w, r)))
-					return
-				}
-				fullURL := r.URL.RequestURI() // this includes query parameters
-				config.Render(r, w, component(config.getCachedOrUpdate(fullURL, w, r)))
-			})
-		case PUT:
-			r.Put(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				if !isLocal {
-					w.Header().Set("Cache-Control", "max-age=31536000")
-					config.Render(r, w, component(config.Middleware(w, r)))
-					return
-				}
-				fullURL := r.URL.RequestURI() // this includes query parameters
-				config.Render(r, w, component(config.getCachedOrUpdate(fullURL, w, r)))
-			})
-		case PATCH:
-			r.Patch(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				if !isLocal {
-					w.Header().Set("Cache-Control", "max-age=31536000")
-					config.Render(r, w, component(config.Middleware(w, r)))
-					return
-				}
-				fullURL := r.URL.RequestURI() // this includes query parameters
-				config.Render(r, w, component(config.getCachedOrUpdate(fullURL, w, r)))
-			})
-		case DELETE:
-			r.Delete(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				if !isLocal {
-					w.Header().Set("Cache-Control", "max-age=31536000")
-					config.Render(r, w, component(config.Middleware(w, r)))
-					return
-				}
-				fullURL := r.URL.RequestURI() // this includes query parameters
-				config.Render(r, w, component(config.getCachedOrUpdate(fullURL, w, r)))
-			})
-		}
-
-	}
-
-	if config.Type == DYNAMIC {
-		switch config.HttpMethod {
-		case GET:
-			r.Get(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				config.Render(r, w, component(config.Middleware(w, r)))
-			})
-		case POST:
-			r.Post(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				config.Render(r, w, component(config.Middleware(w, r)))
-			})
-		case PUT:
-			r.Put(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				config.Render(r, w, component(config.Middleware(w, r)))
-			})
-		case PATCH:
-			r.Patch(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				config.Render(r, w, component(config.Middleware(w, r)))
-			})
-		case DELETE:
-			r.Delete(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				config.Render(r, w, component(config.Middleware(w, r)))
-			})
-		}
-
-	}
-
-	if config.Type == ISR {
-		switch config.HttpMethod {
-		case GET:
-			r.Get(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				if !isLocal {
-					w.Header().Set("Cache-Control", fmt.Sprintf(
-						"max-age=%v, stale-while-revalidate=%v, stale-if-error=%v",
-						config.RevalidateInSec, config.RevalidateInSec, config.RevalidateInSec,
-					))
-					config.Render(r, w, component(config.Middleware(w, r)))
-					return
-				}
-				fullURL := r.URL.RequestURI() // this includes query parameters
-				config.Render(r, w, component(config.getISRCachedOrUpdate(fullURL, w, r)))
-
-			})
-		case POST:
-			r.Post(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				if !isLocal {
-					w.Header().Set("Cache-Control", fmt.Sprintf(
-						"max-age=%v, stale-while-revalidate=%v, stale-if-error=%v",
-						config.RevalidateInSec, config.RevalidateInSec, config.RevalidateInSec,
-					))
-					config.Render(r, w, component(config.Middleware(w, r)))
-					return
-				}
-				fullURL := r.URL.RequestURI() // this includes query parameters
-				config.Render(r, w, component(config.getISRCachedOrUpdate(fullURL, w, r)))
-			})
-		case PUT:
-			r.Put(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				if !isLocal {
-					w.Header().Set("Cache-Control", fmt.Sprintf(
-						"max-age=%v, stale-while-revalidate=%v, stale-if-error=%v",
-						config.RevalidateInSec, config.RevalidateInSec, config.RevalidateInSec,
-					))
-					config.Render(r, w, component(config.Middleware(w, r)))
-					return
-				}
-				fullURL := r.URL.RequestURI() // this includes query parameters
-				config.Render(r, w, component(config.getISRCachedOrUpdate(fullURL, w, r)))
-			})
-		case PATCH:
-			r.Patch(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				if !isLocal {
-					w.Header().Set("Cache-Control", fmt.Sprintf(
-						"max-age=%v, stale-while-revalidate=%v, stale-if-error=%v",
-						config.RevalidateInSec, config.RevalidateInSec, config.RevalidateInSec,
-					))
-					config.Render(r, w, component(config.Middleware(w, r)))
-					return
-				}
-				fullURL := r.URL.RequestURI() // this includes query parameters
-				config.Render(r, w, component(config.getISRCachedOrUpdate(fullURL, w, r)))
-			})
-		case DELETE:
-			r.Delete(httpPath, func(w http.ResponseWriter, r *http.Request) {
-				if !isLocal {
-					w.Header().Set("Cache-Control", fmt.Sprintf(
-						"max-age=%v, stale-while-revalidate=%v, stale-if-error=%v",
-						config.RevalidateInSec, config.RevalidateInSec, config.RevalidateInSec,
-					))
-					config.Render(r, w, component(config.Middleware(w, r)))
-					return
-				}
-				fullURL := r.URL.RequestURI() // this includes query parameters
-				config.Render(r, w, component(config.getISRCachedOrUpdate(fullURL, w, r)))
-			})
-		}
-	}
 
-}
-
-func (config *RouteConfig[T]) getISRCachedOrUpdate(fullURL string, w http.ResponseWriter, r *http.Request) T {
-	localCacheMutex.Lock()
-	defer localCacheMutex.Unlock()
+	switch config.Type {
+	case STATIC, ISR, DYNAMIC:
+	default:
+		return
+	}
 
-	cacheItem, exists := localCacheValue[fullURL]
-	now := time.Now()
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := &middlewareWriter{ResponseWriter: w}
+		props, lastModified := config.getProps(isLocal, writer, r)
+		// The Middleware already answered, e.g. with a redirect to a login page
+		if writer.wroteResponse {
+			return
+		}
+		if config.isCacheable() {
+			config.CacheKey.SetVary(w)
+		}
+		w.Header().Set("Cache-Control", config.getCachePolicy().String())
+		if !lastModified.IsZero() {
+			w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+		}
 
-	if !exists {
-		// Cache miss: generate and store new data with revalidation time
-		newData := config.Middleware(w, r)
-		localCacheValue[fullURL] = isrLocalCache{
-			data:       newData,
-			revalidate: now.Add(time.Duration(config.RevalidateInSec) * time.Second),
+		ctx, span := tracer.Start(r.Context(), "gothic.render", config.spanAttributes())
+		defer span.End()
+		if config.isCacheable() {
+			ctx = templ.WithNonce(context.WithValue(ctx, sharedRenderKey{}, true), "")
+		}
+		r = r.WithContext(ctx)
+		start := time.Now()
+		if !config.usesETag() {
+			config.Render(r, w, component(props))
+		} else {
+			config.renderWithETag(r, w, component(props), lastModified)
 		}
-		return newData
+		metrics.ObserveRender(metrics.RoutePattern(r), config.Type.String(), time.Since(start))
+	})
+	if config.RateLimit != nil {
+		handler = config.RateLimit.Handler(config.HttpMethod.String()+" "+httpPath, handler)
 	}
+	r.Method(config.HttpMethod.String(), httpPath, handler)
+}
 
-	// Try to assert the cache item to expected struct type
-	cached, ok := cacheItem.(isrLocalCache)
-	if !ok {
-		// Cache is corrupted or wrong type; regenerate
-		newData := config.Middleware(w, r)
-		localCacheValue[fullURL] = isrLocalCache{
-			data:       newData,
-			revalidate: now.Add(time.Duration(config.RevalidateInSec) * time.Second),
-		}
-		return newData
+// getProps runs the route Middleware, going through the local cache when the
+// route is cacheable. The returned time is when the props were generated, or
+// zero for responses that must not be cached.
+func (config *RouteConfig[T]) getProps(isLocal bool, w *middlewareWriter, r *http.Request) (T, time.Time) {
+	switch {
+	case !config.isCacheable():
+		return config.runMiddleware(w, r), time.Time{}
+	case !isLocal && config.Type == STATIC:
+		return config.runMiddleware(w, r), serverStartTime
+	case !isLocal:
+		// ISR pages are regenerated by the CDN, every origin hit is fresh
+		return config.runMiddleware(w, r), time.Now()
+	default:
+		return config.getLocalCachedOrUpdate(config.CacheKey.Key(r), w, r)
 	}
+}
 
-	// If revalidation time has passed, refresh the data
-	if cached.revalidate.Before(now) {
-		newData := config.Middleware(w, r)
-		localCacheValue[fullURL] = isrLocalCache{
-			data:       newData,
-			revalidate: now.Add(time.Duration(config.RevalidateInSec) * time.Second),
+// getLocalCachedOrUpdate serves the props cached for key, running the
+// Middleware when they are missing or, for ISR pages, expired. The Middleware
+// runs outside localCacheMutex, so a slow page never blocks the others, and
+// concurrent misses of the same key wait for a single run.
+func (config *RouteConfig[T]) getLocalCachedOrUpdate(key string, w *middlewareWriter, r *http.Request) (T, time.Time) {
+	ctx, span := tracer.Start(r.Context(), "gothic.cache", config.spanAttributes())
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	for {
+		localCacheMutex.Lock()
+		cached, exists := localCacheValue[key]
+		// The type check guards against a nil or mismatched value stored by
+		// another route
+		if val, ok := cached.data.(T); exists && ok && config.isFresh(cached, time.Now()) {
+			localCacheMutex.Unlock()
+			config.recordCacheResult(span, r, cacheHit)
+			return val, cached.generatedAt
 		}
-		return newData
-	}
+		running, isRunning := localCacheRuns[key]
+		if !isRunning {
+			localCacheRuns[key] = make(chan struct{})
+		}
+		localCacheMutex.Unlock()
 
-	// Safe type assertion with check to avoid panic if cached.data is nil
-	if val, ok := cached.data.(T); ok {
-		return val
+		if !isRunning {
+			if exists {
+				config.recordCacheResult(span, r, cacheStale)
+			} else {
+				config.recordCacheResult(span, r, cacheMiss)
+			}
+			return config.updateLocalCache(key, w, r)
+		}
+		// Another request is generating the props, use them once it is done
+		<-running
 	}
+}
+
+// updateLocalCache runs the Middleware for the request owning the
+// localCacheRuns entry of key and releases the requests waiting for it.
+func (config *RouteConfig[T]) updateLocalCache(key string, w *middlewareWriter, r *http.Request) (T, time.Time) {
+	defer func() {
+		localCacheMutex.Lock()
+		close(localCacheRuns[key])
+		delete(localCacheRuns, key)
+		localCacheMutex.Unlock()
+	}()
 
-	// Fallback: data is nil or not of type T
-	newData := config.Middleware(w, r)
-	localCacheValue[fullURL] = isrLocalCache{
-		data:       newData,
-		revalidate: now.Add(time.Duration(config.RevalidateInSec) * time.Second),
+	now := time.Now()
+	result := config.runMiddleware(w, r)
+	// A response of the Middleware, such as a redirect, is not the page
+	if w.wroteResponse {
+		return result, now
 	}
-	return newData
+	localCacheMutex.Lock()
+	localCacheValue[key] = localCache{
+		data:        result,
+		route:       metrics.RoutePattern(r),
+		generatedAt: now,
+		revalidate:  now.Add(time.Duration(config.RevalidateInSec) * time.Second),
+	}
+	localCacheMutex.Unlock()
+	return result, now
 }
 
-func (config *RouteConfig[T]) getCachedOrUpdate(fullURL string, w http.ResponseWriter, r *http.Request) T {
-	localCacheMutex.Lock()
-	defer localCacheMutex.Unlock()
+// isFresh reports whether cached props may still be served. STATIC props live
+// until the server restarts, ISR props until their revalidation time.
+func (config *RouteConfig[T]) isFresh(cached localCache, now time.Time) bool {
+	return config.Type == STATIC || !cached.revalidate.Before(now)
+}
 
-	if cached, exists := localCacheValue[fullURL]; exists {
-		// Safe type assertion with check to avoid panic
-		if val, ok := cached.(T); ok {
-			return val
-		}
-		// Fallback if data in cache is nil or wrong type
-	}
+// middlewareWriter records whether a route Middleware wrote a response of its
+// own, in which case the page is not rendered.
+type middlewareWriter struct {
+	http.ResponseWriter
+	wroteResponse bool
+}
+
+func (writer *middlewareWriter) WriteHeader(status int) {
+	writer.wroteResponse = true
+	writer.ResponseWriter.WriteHeader(status)
+}
 
-	// Cache miss or failed assertion: generate and store
-	result := config.Middleware(w, r)
-	localCacheValue[fullURL] = result
-	return result
+func (writer *middlewareWriter) Write(data []byte) (int, error) {
+	writer.wroteResponse = true
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *middlewareWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
 }
 
 func (config *RouteConfig[T]) Render(r *http.Request, w http.ResponseWriter, component templ.Component) error {
@@ -298,22 +279,49 @@ func (config *RouteConfig[T]) Render(r *http.Request, w http.ResponseWriter, com
 
 type ApiRouteConfig struct {
 	HttpMethod HttpMethod
+	// RateLimit answers 429 once a client exceeds it.
+	RateLimit *ratelimit.Limit
+	// CORS replaces the "cors" section of gothic-config.json for this route.
+	CORS *cors.Config
 }
 
-func (config *ApiRouteConfig) RegisterRoute(r chi.Router, httpPath string, fn func(w http.ResponseWriter, r *http.Request)) {
+// RegisterRoute adds the route to r. It returns an error, and registers
+// nothing, when the CORS config of the route is invalid.
+func (config *ApiRouteConfig) RegisterRoute(r chi.Router, httpPath string, fn func(w http.ResponseWriter, r *http.Request)) error {
+	corsConfig := config.corsConfig()
+	if err := corsConfig.Validate(); err != nil {
+		return fmt.Errorf("error registering %s %s: %w", config.HttpMethod, httpPath, err)
+	}
+	var handler http.Handler = http.HandlerFunc(fn)
+	if config.RateLimit != nil {
+		handler = config.RateLimit.Handler(config.HttpMethod.String()+" "+httpPath, handler)
+	}
+	// Outside the rate limit so browsers can read 429 responses too
+	if corsConfig.Enabled() {
+		handler = corsConfig.Handler(handler)
+	}
 	switch config.HttpMethod {
-	case GET:
-		r.Get(httpPath, fn)
-	case POST:
-		r.Post(httpPath, fn)
-	case PUT:
-		r.Put(httpPath, fn)
-	case PATCH:
-		r.Patch(httpPath, fn)
-	case DELETE:
-		r.Delete(httpPath, fn)
+	case GET, POST, PUT, PATCH, DELETE:
+		r.Method(config.HttpMethod.String(), httpPath, handler)
+	}
+	return nil
+}
+
+// RegisterPreflight answers the OPTIONS requests browsers send before cross
+// origin calls to the route. Nothing is registered while CORS is disabled.
+func (config *ApiRouteConfig) RegisterPreflight(r chi.Router, httpPath string) {
+	corsConfig := config.corsConfig()
+	if !corsConfig.Enabled() {
+		return
 	}
+	r.Options(httpPath, corsConfig.Preflight(config.HttpMethod.String()).ServeHTTP)
+}
 
+func (config *ApiRouteConfig) corsConfig() cors.Config {
+	if config.CORS != nil {
+		return *config.CORS
+	}
+	return cors.Default()
 }
 
 func (config *ApiRouteConfig) Render(r *http.Request, w http.ResponseWriter, component templ.Component) error {
@@ -327,6 +335,9 @@ type RouteTemplate struct {
 	ConfigPackageName string
 	HttpPath          string
 	OriginFile        string
+	CacheKey          CacheKey
+	// CORS is set for API routes overriding the global CORS config.
+	CORS bool
 }
 
 type Imports struct {
@@ -438,6 +449,10 @@ func (helper *FileBasedRouteHelper) collectApiRoutesInfo(goModName string) error
 				route.FunctionName = funcMatch[1]
 			}
 
+			if route.CORS, err = helper.parseCORS(content, route.ConfigName); err != nil {
+				return fmt.Errorf("failed to read the CORS config of %s: %w", path, err)
+			}
+
 			route.HttpPath = helper.normalizeHttpPath(path)
 			if route.FunctionName != "" {
 				helper.TemplateInfo.ApiRoutes = append(helper.TemplateInfo.ApiRoutes, route)
@@ -493,6 +508,9 @@ func (helper *FileBasedRouteHelper) collectComponentsInfo(goModName string) erro
 			if len(funcMatch) > 1 {
 				route.FunctionName = funcMatch[1]
 			}
+			if route.CacheKey, err = helper.parseCacheKey(content, route.ConfigName); err != nil {
+				return fmt.Errorf("failed to read the cache key of %s: %w", path, err)
+			}
 
 			route.HttpPath = helper.normalizeHttpPath(path)
 			if route.FunctionName != "" {
@@ -549,6 +567,9 @@ func (helper *FileBasedRouteHelper) collectPageInfo(goModName string) error {
 			if len(funcMatch) > 1 {
 				route.FunctionName = funcMatch[1]
 			}
+			if route.CacheKey, err = helper.parseCacheKey(content, route.ConfigName); err != nil {
+				return fmt.Errorf("failed to read the cache key of %s: %w", path, err)
+			}
 
 			route.HttpPath = helper.normalizeHttpPath(path)
 			if route.FunctionName != "" {
@@ -563,6 +584,225 @@ func (helper *FileBasedRouteHelper) collectPageInfo(goModName string) error {
 	return nil
 }
 
+// parseCacheKey reads the CacheKey declared in the configName route config of
+// a route file so the CloudFront cache policy can be generated from it. Only
+// slices of string literals are understood, like the local cache they must
+// be known before the app runs.
+func (helper *FileBasedRouteHelper) parseCacheKey(content []byte, configName string) (CacheKey, error) {
+	var key CacheKey
+	file, err := parser.ParseFile(token.NewFileSet(), "", content, parser.SkipObjectResolution)
+	if err != nil {
+		return key, err
+	}
+	config := helper.findVarValue(file, configName)
+	if config == nil {
+		return key, nil
+	}
+	cacheKey, ok := helper.compositeField(config, "CacheKey").(*ast.CompositeLit)
+	if !ok {
+		return key, nil
+	}
+	for _, element := range cacheKey.Elts {
+		field, ok := element.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		name, ok := field.Key.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		values, err := helper.stringSlice(field.Value)
+		if err != nil {
+			return key, fmt.Errorf("CacheKey.%s: %w", name.Name, err)
+		}
+		switch name.Name {
+		case "QueryParams":
+			key.QueryParams = values
+		case "IgnoredQueryParams":
+			key.IgnoredQueryParams = values
+		case "Headers":
+			key.Headers = values
+		case "Cookies":
+			key.Cookies = values
+		}
+	}
+	return key, nil
+}
+
+// parseCORS reports whether the configName API route config of a route file
+// sets its own CORS config.
+func (helper *FileBasedRouteHelper) parseCORS(content []byte, configName string) (bool, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", content, parser.SkipObjectResolution)
+	if err != nil {
+		return false, err
+	}
+	config := helper.findVarValue(file, configName)
+	if config == nil {
+		return false, nil
+	}
+	value := helper.compositeField(config, "CORS")
+	if ident, ok := value.(*ast.Ident); ok && ident.Name == "nil" {
+		return false, nil
+	}
+	return value != nil, nil
+}
+
+// findVarValue returns the composite literal assigned to the package level
+// variable name.
+func (helper *FileBasedRouteHelper) findVarValue(file *ast.File, name string) *ast.CompositeLit {
+	for _, declaration := range file.Decls {
+		general, ok := declaration.(*ast.GenDecl)
+		if !ok || general.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range general.Specs {
+			value, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			for i, ident := range value.Names {
+				if ident.Name != name || i >= len(value.Values) {
+					continue
+				}
+				literal, _ := value.Values[i].(*ast.CompositeLit)
+				return literal
+			}
+		}
+	}
+	return nil
+}
+
+// compositeField returns the value of the keyed field name in literal.
+func (helper *FileBasedRouteHelper) compositeField(literal *ast.CompositeLit, name string) ast.Expr {
+	for _, element := range literal.Elts {
+		if field, ok := element.(*ast.KeyValueExpr); ok {
+			if key, ok := field.Key.(*ast.Ident); ok && key.Name == name {
+				return field.Value
+			}
+		}
+	}
+	return nil
+}
+
+// stringSlice evaluates a []string{...} literal of string constants. nil is
+// kept nil, since a nil QueryParams means every parameter.
+func (helper *FileBasedRouteHelper) stringSlice(expr ast.Expr) ([]string, error) {
+	if ident, ok := expr.(*ast.Ident); ok && ident.Name == "nil" {
+		return nil, nil
+	}
+	literal, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil, fmt.Errorf("must be a []string literal")
+	}
+	values := []string{}
+	for _, element := range literal.Elts {
+		basic, ok := element.(*ast.BasicLit)
+		if !ok || basic.Kind != token.STRING {
+			return nil, fmt.Errorf("must only hold string literals")
+		}
+		value, err := strconv.Unquote(basic.Value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// CachePolicy merges the cache keys of every page and component route into the
+// single CloudFront cache policy used by the server origin. The merged key is a
+// superset of each route's key, so the edge never serves a response cached for
+// a different request.
+func (helper *FileBasedRouteHelper) CachePolicy() helpers.CachePolicyTemplateInfo {
+	var policy helpers.CachePolicyTemplateInfo
+	allowed := map[string]bool{}
+	var ignored map[string]bool
+	headers := map[string]bool{}
+	cookies := map[string]bool{}
+
+	for _, route := range helper.TemplateInfo.Routes {
+		key := route.CacheKey
+		if key.QueryParams != nil {
+			for _, name := range key.QueryParams {
+				allowed[name] = true
+			}
+		} else {
+			// Only parameters ignored by every route can stay out of the key
+			routeIgnored := map[string]bool{}
+			for _, name := range key.ignoredQueryParams() {
+				if ignored == nil || ignored[name] {
+					routeIgnored[name] = true
+				}
+			}
+			ignored = routeIgnored
+		}
+		for _, name := range key.Headers {
+			headers[name] = true
+		}
+		for _, name := range key.Cookies {
+			cookies[name] = true
+		}
+	}
+
+	switch {
+	case ignored != nil:
+		for name := range ignored {
+			if !allowed[name] {
+				policy.QueryStrings = append(policy.QueryStrings, name)
+			}
+		}
+		policy.QueryStringBehavior = "allExcept"
+		if len(policy.QueryStrings) == 0 {
+			policy.QueryStringBehavior = "all"
+		}
+	case len(allowed) > 0:
+		for name := range allowed {
+			policy.QueryStrings = append(policy.QueryStrings, name)
+		}
+		policy.QueryStringBehavior = "whitelist"
+	default:
+		policy.QueryStringBehavior = "none"
+	}
+	for name := range headers {
+		policy.Headers = append(policy.Headers, name)
+	}
+	for name := range cookies {
+		policy.Cookies = append(policy.Cookies, name)
+	}
+
+	sort.Strings(policy.QueryStrings)
+	sort.Strings(policy.Headers)
+	sort.Strings(policy.Cookies)
+	return policy
+}
+
+// RoutesForTemplFiles returns the paths of the page and component routes
+// rendered by the given .templ files.
+func (helper *FileBasedRouteHelper) RoutesForTemplFiles(files []string) []string {
+	origins := map[string]bool{}
+	for _, file := range files {
+		origins[filepath.Clean(strings.TrimSuffix(file, ".templ")+"_templ.go")] = true
+	}
+	paths := []string{}
+	for _, route := range helper.TemplateInfo.Routes {
+		if origins[filepath.Clean(route.OriginFile)] {
+			paths = append(paths, route.HttpPath)
+		}
+	}
+	return paths
+}
+
+// UsesCORS reports whether any API route sets its own CORS config, in which
+// case CloudFront must keep the Origin header in the cache key.
+func (helper *FileBasedRouteHelper) UsesCORS() bool {
+	for _, route := range helper.TemplateInfo.ApiRoutes {
+		if route.CORS {
+			return true
+		}
+	}
+	return false
+}
+
 func (helper *FileBasedRouteHelper) pruneMissingFiles() {
 	validFiles := make(map[string]bool)
 
@@ -0,0 +1,295 @@
+package helpers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/felipegenef/gothicframework/pkg/helpers/cors"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMiddlewareResponseSkipsRender(t *testing.T) {
+	tests := []struct {
+		name         string
+		middleware   func(w http.ResponseWriter, r *http.Request) string
+		wantStatus   int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name: "props",
+			middleware: func(w http.ResponseWriter, r *http.Request) string {
+				return "props"
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "page props",
+		},
+		{
+			name: "redirect",
+			middleware: func(w http.ResponseWriter, r *http.Request) string {
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return ""
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/login",
+		},
+		{
+			name: "error status",
+			middleware: func(w http.ResponseWriter, r *http.Request) string {
+				w.WriteHeader(http.StatusNotFound)
+				return ""
+			},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	t.Setenv("LOCAL_SERVE", "")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := RouteConfig[string]{Type: DYNAMIC, HttpMethod: GET, Middleware: test.middleware}
+			router := chi.NewRouter()
+			config.RegisterRoute(router, "/account", func(props string) templ.Component {
+				return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+					_, err := io.WriteString(w, "page "+props)
+					return err
+				})
+			})
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/account", nil))
+
+			if recorder.Code != test.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, test.wantStatus)
+			}
+			if got := recorder.Header().Get("Location"); got != test.wantLocation {
+				t.Errorf("Location = %q, want %q", got, test.wantLocation)
+			}
+			body := recorder.Body.String()
+			if test.wantBody == "" && strings.Contains(body, "page") {
+				t.Errorf("body = %q, the page rendered after the Middleware answered", body)
+			}
+			if test.wantBody != "" && body != test.wantBody {
+				t.Errorf("body = %q, want %q", body, test.wantBody)
+			}
+		})
+	}
+}
+
+func TestSharedRenderFlag(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     RouteConfig[string]
+		method     string
+		wantShared bool
+	}{
+		{name: "static", config: RouteConfig[string]{Type: STATIC, HttpMethod: GET}, method: http.MethodGet, wantShared: true},
+		{name: "isr", config: RouteConfig[string]{Type: ISR, HttpMethod: GET, RevalidateInSec: 60}, method: http.MethodGet, wantShared: true},
+		{name: "dynamic", config: RouteConfig[string]{Type: DYNAMIC, HttpMethod: GET}, method: http.MethodGet, wantShared: false},
+		{name: "dynamic with etag", config: RouteConfig[string]{Type: DYNAMIC, HttpMethod: GET, ETag: true}, method: http.MethodGet, wantShared: false},
+		{name: "static post", config: RouteConfig[string]{Type: STATIC, HttpMethod: POST}, method: http.MethodPost, wantShared: false},
+	}
+	t.Setenv("LOCAL_SERVE", "")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.config.Middleware = func(w http.ResponseWriter, r *http.Request) string {
+				return ""
+			}
+			var shared, rendered bool
+			router := chi.NewRouter()
+			test.config.RegisterRoute(router, "/page", func(props string) templ.Component {
+				return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+					shared, rendered = IsSharedRender(ctx), true
+					return nil
+				})
+			})
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(test.method, "/page", nil))
+
+			if !rendered {
+				t.Fatal("the page was not rendered")
+			}
+			if shared != test.wantShared {
+				t.Errorf("IsSharedRender() = %v, want %v", shared, test.wantShared)
+			}
+		})
+	}
+	if IsSharedRender(context.Background()) {
+		t.Error("IsSharedRender() is true outside a route render")
+	}
+}
+
+func TestLocalCacheRunsMiddlewareOncePerKey(t *testing.T) {
+	var runs atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	config := RouteConfig[string]{Type: STATIC, HttpMethod: GET, Middleware: func(w http.ResponseWriter, r *http.Request) string {
+		if runs.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		return r.URL.Path
+	}}
+	t.Cleanup(func() { clearLocalCache("/single-flight", "/other") })
+
+	var wg sync.WaitGroup
+	results := make([]string, 10)
+	get := func(i int) {
+		defer wg.Done()
+		request := httptest.NewRequest(http.MethodGet, "/single-flight", nil)
+		results[i], _ = config.getLocalCachedOrUpdate("/single-flight", &middlewareWriter{ResponseWriter: httptest.NewRecorder()}, request)
+	}
+	wg.Add(1)
+	go get(0)
+	<-started
+	for i := 1; i < len(results); i++ {
+		wg.Add(1)
+		go get(i)
+	}
+
+	// Another key is not blocked by the slow run
+	request := httptest.NewRequest(http.MethodGet, "/other", nil)
+	if props, _ := config.getLocalCachedOrUpdate("/other", &middlewareWriter{ResponseWriter: httptest.NewRecorder()}, request); props != "/other" {
+		t.Errorf("props of another key = %q, want /other", props)
+	}
+
+	close(release)
+	wg.Wait()
+	if got := runs.Load(); got != 2 {
+		t.Errorf("Middleware ran %d times, want once per key", got)
+	}
+	for i, props := range results {
+		if props != "/single-flight" {
+			t.Errorf("request %d got props %q", i, props)
+		}
+	}
+}
+
+func TestLocalCacheSkipsMiddlewareResponses(t *testing.T) {
+	var runs int
+	config := RouteConfig[string]{Type: STATIC, HttpMethod: GET, Middleware: func(w http.ResponseWriter, r *http.Request) string {
+		runs++
+		if runs == 1 {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+		}
+		return "props"
+	}}
+	t.Cleanup(func() { clearLocalCache("/redirect") })
+
+	for i := 0; i < 3; i++ {
+		request := httptest.NewRequest(http.MethodGet, "/redirect", nil)
+		config.getLocalCachedOrUpdate("/redirect", &middlewareWriter{ResponseWriter: httptest.NewRecorder()}, request)
+	}
+	// The redirect is not cached, the next request runs the Middleware again
+	// and caches its props
+	if runs != 2 {
+		t.Errorf("Middleware ran %d times, want 2", runs)
+	}
+}
+
+func TestIsFresh(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		configType ConfigType
+		revalidate time.Time
+		want       bool
+	}{
+		{name: "static", configType: STATIC, revalidate: now.Add(-time.Hour), want: true},
+		{name: "isr before revalidation", configType: ISR, revalidate: now.Add(time.Second), want: true},
+		{name: "isr at revalidation", configType: ISR, revalidate: now, want: true},
+		{name: "isr after revalidation", configType: ISR, revalidate: now.Add(-time.Second), want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := RouteConfig[string]{Type: test.configType}
+			if got := config.isFresh(localCache{revalidate: test.revalidate}, now); got != test.want {
+				t.Errorf("isFresh() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseCORS(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    bool
+		wantErr bool
+	}{
+		{name: "own config", source: `var ItemsConfig = routes.ApiRouteConfig{HttpMethod: routes.POST, CORS: &cors.Config{AllowedOrigins: []string{"https://example.com"}}}`, want: true},
+		{name: "config variable", source: `var ItemsConfig = routes.ApiRouteConfig{CORS: &partnerCORS}`, want: true},
+		{name: "nil", source: `var ItemsConfig = routes.ApiRouteConfig{CORS: nil}`},
+		{name: "no cors", source: `var ItemsConfig = routes.ApiRouteConfig{HttpMethod: routes.GET}`},
+		{
+			name: "commented out",
+			source: `var ItemsConfig = routes.ApiRouteConfig{
+	HttpMethod: routes.GET,
+	// CORS: &cors.Config{AllowedOrigins: []string{"*"}},
+}`,
+		},
+		{
+			name: "in a string",
+			source: `var ItemsConfig = routes.ApiRouteConfig{HttpMethod: routes.GET}
+
+var example = "CORS: &cors.Config{}"`,
+		},
+		{
+			name: "other config",
+			source: `var OtherConfig = routes.ApiRouteConfig{CORS: &cors.Config{}}
+
+var ItemsConfig = routes.ApiRouteConfig{HttpMethod: routes.GET}`,
+		},
+		{name: "invalid go", source: `var ItemsConfig = routes.ApiRouteConfig{`, wantErr: true},
+	}
+	helper := NewFileBasedRouteHelper()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := helper.parseCORS([]byte("package api\n\n"+test.source+"\n"), "ItemsConfig")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseCORS() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("parseCORS() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestApiRouteRejectsInvalidCORS(t *testing.T) {
+	tests := []struct {
+		name    string
+		cors    *cors.Config
+		wantErr bool
+	}{
+		{name: "default", cors: nil},
+		{name: "listed origin with credentials", cors: &cors.Config{AllowedOrigins: []string{"https://example.com"}, AllowCredentials: true}},
+		{name: "any origin with credentials", cors: &cors.Config{AllowedOrigins: []string{"*"}, AllowCredentials: true}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := ApiRouteConfig{HttpMethod: GET, CORS: test.cors}
+			router := chi.NewRouter()
+			err := config.RegisterRoute(router, "/api/items", func(w http.ResponseWriter, r *http.Request) {})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("RegisterRoute() error = %v, wantErr %v", err, test.wantErr)
+			}
+			registered := chi.NewRouteContext()
+			if found := router.Match(registered, http.MethodGet, "/api/items"); found == test.wantErr {
+				t.Errorf("route registered = %v, want %v", found, !test.wantErr)
+			}
+		})
+	}
+}
+
+func clearLocalCache(keys ...string) {
+	localCacheMutex.Lock()
+	defer localCacheMutex.Unlock()
+	for _, key := range keys {
+		delete(localCacheValue, key)
+	}
+}
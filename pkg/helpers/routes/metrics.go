@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"net/http"
+
+	"github.com/felipegenef/gothicframework/pkg/helpers/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var cacheEntriesDesc = prometheus.NewDesc(
+	"gothic_cache_entries",
+	"Pages held in the local cache, by route pattern.",
+	[]string{"route"}, nil,
+)
+
+// cacheCollector reports the size of localCacheValue on every scrape.
+type cacheCollector struct{}
+
+func init() {
+	metrics.Registry.MustRegister(cacheCollector{})
+}
+
+func (cacheCollector) Describe(descriptions chan<- *prometheus.Desc) {
+	descriptions <- cacheEntriesDesc
+}
+
+func (cacheCollector) Collect(values chan<- prometheus.Metric) {
+	localCacheMutex.RLock()
+	entries := map[string]int{}
+	for _, cached := range localCacheValue {
+		entries[cached.route]++
+	}
+	localCacheMutex.RUnlock()
+
+	for route, count := range entries {
+		values <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(count), route)
+	}
+}
+
+// recordCacheResult tags the cache span and counts the lookup.
+func (config *RouteConfig[T]) recordCacheResult(span trace.Span, r *http.Request, result string) {
+	span.SetAttributes(cacheResultAttribute.String(result))
+	metrics.CountCacheLookup(metrics.RoutePattern(r), config.Type.String(), result)
+}
@@ -0,0 +1,35 @@
+package helpers
+
+import (
+	"net/http"
+
+	"github.com/felipegenef/gothicframework/pkg/helpers/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer(telemetry.TracerName)
+
+const cacheResultAttribute = attribute.Key("gothic.cache.result")
+
+const (
+	cacheHit   = "hit"
+	cacheMiss  = "miss"
+	cacheStale = "stale"
+)
+
+// runMiddleware calls the route Middleware inside a span, so slow STATIC and
+// ISR regenerations stand out in traces.
+func (config *RouteConfig[T]) runMiddleware(w http.ResponseWriter, r *http.Request) T {
+	ctx, span := tracer.Start(r.Context(), "gothic.middleware", config.spanAttributes())
+	defer span.End()
+	return config.Middleware(w, r.WithContext(ctx))
+}
+
+func (config *RouteConfig[T]) spanAttributes() trace.SpanStartEventOption {
+	return trace.WithAttributes(
+		attribute.String("gothic.render_type", config.Type.String()),
+		attribute.String("gothic.http_method", config.HttpMethod.String()),
+	)
+}
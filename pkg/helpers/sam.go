@@ -28,8 +28,13 @@ func (helper *AwsSamHelper) Build() error {
 	return err
 }
 
-func (helper *AwsSamHelper) Deploy(stage string, stackName string, awsProfile string) error {
-	samDeployCMD := exec.Command("sam", "deploy", "--stack-name", stackName+"-"+stage, "--parameter-overrides", "Stage="+stage, "--profile", awsProfile)
+// Deploy deploys the stack of stage. parameters are extra "Name=value"
+// template parameter overrides.
+func (helper *AwsSamHelper) Deploy(stage string, stackName string, awsProfile string, parameters ...string) error {
+	args := []string{"deploy", "--stack-name", stackName + "-" + stage, "--parameter-overrides", "Stage=" + stage}
+	args = append(args, parameters...)
+	args = append(args, "--profile", awsProfile)
+	samDeployCMD := exec.Command("sam", args...)
 	samDeployCMD.Stdout = os.Stdout
 	samDeployCMD.Stdin = os.Stdin
 	samDeployCMD.Stderr = os.Stderr
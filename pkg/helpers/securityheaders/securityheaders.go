@@ -0,0 +1,125 @@
+package securityheaders
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/felipegenef/gothicframework/pkg/helpers/config"
+)
+
+// HSTSConfig controls the Strict-Transport-Security header.
+type HSTSConfig struct {
+	Enabled           bool `json:"enabled"`
+	MaxAgeInSec       int  `json:"maxAgeInSec"`
+	IncludeSubdomains bool `json:"includeSubdomains"`
+	Preload           bool `json:"preload"`
+}
+
+// Config is the "securityHeaders" section of gothic-config.json. The deploy
+// command applies the same values to a CloudFront response headers policy,
+// so pages cached at the edge carry them too.
+type Config struct {
+	Enabled bool       `json:"enabled"`
+	HSTS    HSTSConfig `json:"hsts"`
+	// ContentTypeOptions sends "X-Content-Type-Options: nosniff".
+	ContentTypeOptions bool `json:"contentTypeOptions"`
+	// FrameOptions is "DENY", "SAMEORIGIN" or empty to skip the header.
+	FrameOptions      string `json:"frameOptions"`
+	ReferrerPolicy    string `json:"referrerPolicy"`
+	PermissionsPolicy string `json:"permissionsPolicy"`
+}
+
+var DefaultConfig = Config{
+	Enabled: true,
+	HSTS: HSTSConfig{
+		Enabled:           true,
+		MaxAgeInSec:       31536000,
+		IncludeSubdomains: true,
+	},
+	ContentTypeOptions: true,
+	FrameOptions:       "SAMEORIGIN",
+	ReferrerPolicy:     "strict-origin-when-cross-origin",
+	PermissionsPolicy:  "camera=(), microphone=(), geolocation=(), payment=()",
+}
+
+// referrerPolicies are the values accepted by browsers and by CloudFront.
+var referrerPolicies = map[string]bool{
+	"":                                true,
+	"no-referrer":                     true,
+	"no-referrer-when-downgrade":      true,
+	"origin":                          true,
+	"origin-when-cross-origin":        true,
+	"same-origin":                     true,
+	"strict-origin":                   true,
+	"strict-origin-when-cross-origin": true,
+	"unsafe-url":                      true,
+}
+
+// LoadConfig reads the "securityHeaders" section of gothic-config.json and
+// validates it: deploy copies it to CloudFront, which would reject the stack.
+func LoadConfig(gothicConfig []byte) (Config, error) {
+	headers, err := config.Section(gothicConfig, "securityHeaders", DefaultConfig)
+	if err != nil {
+		return headers, err
+	}
+	return headers, headers.Validate()
+}
+
+// Validate rejects values CloudFront would not accept.
+func (config Config) Validate() error {
+	switch config.FrameOptions {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		return fmt.Errorf("invalid securityHeaders.frameOptions %q, use DENY, SAMEORIGIN or an empty string", config.FrameOptions)
+	}
+	if !referrerPolicies[config.ReferrerPolicy] {
+		return fmt.Errorf("invalid securityHeaders.referrerPolicy %q", config.ReferrerPolicy)
+	}
+	return nil
+}
+
+// HSTSValue renders the Strict-Transport-Security header value.
+func (config Config) HSTSValue() string {
+	value := "max-age=" + strconv.Itoa(config.HSTS.MaxAgeInSec)
+	if config.HSTS.IncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if config.HSTS.Preload {
+		value += "; preload"
+	}
+	return value
+}
+
+// New returns a middleware setting the configured headers on every response.
+// Handlers may still override them.
+func New(config Config) func(http.Handler) http.Handler {
+	headers := map[string]string{}
+	if config.HSTS.Enabled {
+		headers["Strict-Transport-Security"] = config.HSTSValue()
+	}
+	if config.ContentTypeOptions {
+		headers["X-Content-Type-Options"] = "nosniff"
+	}
+	if config.FrameOptions != "" {
+		headers["X-Frame-Options"] = config.FrameOptions
+	}
+	if config.ReferrerPolicy != "" {
+		headers["Referrer-Policy"] = config.ReferrerPolicy
+	}
+	if config.PermissionsPolicy != "" {
+		headers["Permissions-Policy"] = config.PermissionsPolicy
+	}
+
+	return func(next http.Handler) http.Handler {
+		if !config.Enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range headers {
+				w.Header().Set(name, value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
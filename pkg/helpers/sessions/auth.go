@@ -0,0 +1,90 @@
+package sessions
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+var defaultManager atomic.Pointer[Manager]
+
+// SetDefault makes manager the one used by the package level functions, such
+// as RequireLogin in page configs.
+func SetDefault(manager *Manager) {
+	defaultManager.Store(manager)
+}
+
+// Default returns the Manager set by SetDefault, or nil.
+func Default() *Manager {
+	return defaultManager.Load()
+}
+
+// Get returns the session of r from the default Manager.
+func Get(r *http.Request) (*Session, error) {
+	return mustDefault().Get(r)
+}
+
+// Save persists session with the default Manager.
+func Save(w http.ResponseWriter, r *http.Request, session *Session) error {
+	return mustDefault().Save(w, r, session)
+}
+
+// Login stores userID in the session of r with the default Manager.
+func Login(w http.ResponseWriter, r *http.Request, userID string) error {
+	return mustDefault().Login(w, r, userID)
+}
+
+// Logout destroys the session of r with the default Manager.
+func Logout(w http.ResponseWriter, r *http.Request) error {
+	return mustDefault().Logout(w, r)
+}
+
+// RequireLogin wraps a RouteConfig Middleware so only logged in users see the
+// page. Anonymous visitors are redirected to loginPath with a "next" query
+// param pointing back to the page; HTMX requests get an HX-Redirect header
+// instead. Use it on DYNAMIC routes only, cached pages are shared between
+// users.
+func RequireLogin[T any](loginPath string, middleware func(w http.ResponseWriter, r *http.Request, session *Session) T) func(w http.ResponseWriter, r *http.Request) T {
+	return func(w http.ResponseWriter, r *http.Request) T {
+		var props T
+		session, err := Get(r)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "error loading session", "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return props
+		}
+		if !session.IsAuthenticated() {
+			location := loginPath + "?next=" + url.QueryEscape(r.URL.RequestURI())
+			if r.Header.Get("HX-Request") == "true" {
+				w.Header().Set("HX-Redirect", location)
+				w.WriteHeader(http.StatusNoContent)
+				return props
+			}
+			http.Redirect(w, r, location, http.StatusSeeOther)
+			return props
+		}
+		return middleware(w, r, session)
+	}
+}
+
+// SafeRedirect returns next when it is a path on this site, or fallback. Use
+// it on "next" params to avoid open redirects after login.
+func SafeRedirect(next string, fallback string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return fallback
+	}
+	if parsed, err := url.Parse(next); err != nil || parsed.Host != "" {
+		return fallback
+	}
+	return next
+}
+
+func mustDefault() *Manager {
+	manager := Default()
+	if manager == nil {
+		panic("sessions: SetDefault was not called")
+	}
+	return manager
+}
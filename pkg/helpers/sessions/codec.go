@@ -0,0 +1,71 @@
+package sessions
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var errInvalidCookie = errors.New("invalid session cookie")
+
+// codec seals cookie values with AES-GCM, or only signs them with HMAC-SHA256
+// when encryption is disabled. The cookie name is authenticated too, so a
+// value can't be moved to another cookie.
+type codec struct {
+	encrypt    bool
+	encryption cipher.AEAD
+	signingKey []byte
+}
+
+func newCodec(secret string, encrypt bool) codec {
+	encryptionKey := sha256.Sum256([]byte("gothic-session-encryption:" + secret))
+	signingKey := sha256.Sum256([]byte("gothic-session-signing:" + secret))
+	// A 32 bytes key always yields a valid AES-256 cipher
+	block, _ := aes.NewCipher(encryptionKey[:])
+	encryption, _ := cipher.NewGCM(block)
+	return codec{
+		encrypt:    encrypt,
+		encryption: encryption,
+		signingKey: signingKey[:],
+	}
+}
+
+func (codec codec) seal(name string, plaintext []byte) (string, error) {
+	if !codec.encrypt {
+		payload := base64.RawURLEncoding.EncodeToString(plaintext)
+		return payload + "." + codec.sign(name, payload), nil
+	}
+	nonce := make([]byte, codec.encryption.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := codec.encryption.Seal(nonce, nonce, plaintext, []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (codec codec) open(name string, value string) ([]byte, error) {
+	if !codec.encrypt {
+		payload, signature, found := strings.Cut(value, ".")
+		if !found || !hmac.Equal([]byte(signature), []byte(codec.sign(name, payload))) {
+			return nil, errInvalidCookie
+		}
+		return base64.RawURLEncoding.DecodeString(payload)
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(sealed) < codec.encryption.NonceSize() {
+		return nil, errInvalidCookie
+	}
+	nonce, ciphertext := sealed[:codec.encryption.NonceSize()], sealed[codec.encryption.NonceSize():]
+	return codec.encryption.Open(nil, nonce, ciphertext, []byte(name))
+}
+
+func (codec codec) sign(name string, payload string) string {
+	mac := hmac.New(sha256.New, codec.signingKey)
+	mac.Write([]byte(name + "|" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
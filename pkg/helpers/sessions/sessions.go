@@ -0,0 +1,275 @@
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/felipegenef/gothicframework/pkg/helpers/config"
+)
+
+// Config is the "sessions" section of gothic-config.json. The secret used to
+// sign and encrypt cookies is never read from it: pass it to New from the
+// SESSION_SECRET environment variable instead.
+type Config struct {
+	// Enabled makes the server create the session Manager and deploy pass
+	// SESSION_SECRET to the app, which then refuses to start without it.
+	Enabled     bool   `json:"enabled"`
+	CookieName  string `json:"cookieName"`
+	MaxAgeInSec int    `json:"maxAgeInSec"`
+	Secure      bool   `json:"secure"`
+	// SameSite is "lax", "strict" or "none".
+	SameSite string `json:"sameSite"`
+	// Encrypt hides the cookie content from the browser. When false the cookie
+	// is only signed, so it can't be tampered with but can be read.
+	Encrypt bool `json:"encrypt"`
+	// Store is "cookie" to keep the whole session in the cookie, or "memory"
+	// or "file" to keep it on the server and only send its ID.
+	Store        string `json:"store"`
+	FileStoreDir string `json:"fileStoreDir"`
+}
+
+var DefaultConfig = Config{
+	CookieName:   "gothic_session",
+	MaxAgeInSec:  604800,
+	Secure:       true,
+	SameSite:     "lax",
+	Encrypt:      true,
+	Store:        "cookie",
+	FileStoreDir: "tmp/sessions",
+}
+
+// userIDKey is the session value set by Login.
+const userIDKey = "user_id"
+
+// Session holds string values for one browser. Changes are only persisted by
+// Manager.Save.
+type Session struct {
+	id     string
+	values map[string]string
+}
+
+func (session *Session) Get(key string) string {
+	return session.values[key]
+}
+
+func (session *Session) Set(key string, value string) {
+	session.values[key] = value
+}
+
+func (session *Session) Delete(key string) {
+	delete(session.values, key)
+}
+
+// UserID returns the user set by Login, or an empty string for anonymous
+// visitors.
+func (session *Session) UserID() string {
+	return session.values[userIDKey]
+}
+
+// IsAuthenticated reports whether Login was called for this session.
+func (session *Session) IsAuthenticated() bool {
+	return session.UserID() != ""
+}
+
+// Manager reads and writes sessions. Without a server-side Store the values
+// travel in the cookie itself, which must stay under the 4KB browser limit.
+type Manager struct {
+	config Config
+	codec  codec
+	store  Store
+}
+
+// cookiePayload is sealed into the session cookie. Server-side stores only
+// keep the ID in it.
+type cookiePayload struct {
+	ID        string            `json:"id,omitempty"`
+	Values    map[string]string `json:"v,omitempty"`
+	ExpiresAt int64             `json:"e"`
+}
+
+// maxCookieSize is the limit most browsers put on a single cookie.
+const maxCookieSize = 4096
+
+// LoadConfig reads the cookie and store settings of the "sessions" section of
+// gothic-config.json. The secret is never part of it, see New.
+func LoadConfig(gothicConfig []byte) (Config, error) {
+	return config.Section(gothicConfig, "sessions", DefaultConfig)
+}
+
+// New returns a Manager using the store named in config. The secret must be
+// at least 32 bytes long. An empty secret is an error, except with LOCAL_SERVE
+// set where it is replaced by a random one: that logs everyone out on every
+// restart and would break sessions across Lambda instances.
+func New(config Config, secret string) (*Manager, error) {
+	var store Store
+	switch config.Store {
+	case "", "cookie":
+	case "memory":
+		store = NewMemoryStore()
+	case "file":
+		fileStore, err := NewFileStore(config.FileStoreDir)
+		if err != nil {
+			return nil, err
+		}
+		store = fileStore
+	default:
+		return nil, fmt.Errorf("unknown session store %q, use cookie, memory or file", config.Store)
+	}
+	return NewWithStore(config, secret, store)
+}
+
+// NewWithStore returns a Manager keeping session values in store, such as an
+// SQLStore. A nil store keeps them in the cookie.
+func NewWithStore(config Config, secret string, store Store) (*Manager, error) {
+	if secret == "" {
+		if os.Getenv("LOCAL_SERVE") != "true" {
+			return nil, fmt.Errorf("SESSION_SECRET is not set, deploy passes it from the deploying shell or the sessionSecretParameter of the stage")
+		}
+		slog.Warn("SESSION_SECRET is not set, sessions will not survive a restart")
+		secret = newID() + newID()
+	}
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("session secret must be at least 32 bytes long")
+	}
+	return &Manager{
+		config: config,
+		codec:  newCodec(secret, config.Encrypt),
+		store:  store,
+	}, nil
+}
+
+// Get returns the session of r. Missing, tampered or expired cookies yield a
+// new empty session.
+func (manager *Manager) Get(r *http.Request) (*Session, error) {
+	session := &Session{values: map[string]string{}}
+	cookie, err := r.Cookie(manager.config.CookieName)
+	if err != nil {
+		return session, nil
+	}
+
+	var payload cookiePayload
+	plaintext, err := manager.codec.open(manager.config.CookieName, cookie.Value)
+	if err != nil || json.Unmarshal(plaintext, &payload) != nil || time.Now().Unix() > payload.ExpiresAt {
+		return session, nil
+	}
+
+	if manager.store == nil {
+		if payload.Values != nil {
+			session.values = payload.Values
+		}
+		return session, nil
+	}
+
+	values, err := manager.store.Load(r.Context(), payload.ID)
+	if err != nil {
+		return session, fmt.Errorf("error loading session: %v", err)
+	}
+	if values != nil {
+		session.id = payload.ID
+		session.values = values
+	}
+	return session, nil
+}
+
+// Save persists session and refreshes its cookie, extending its lifetime by
+// MaxAgeInSec.
+func (manager *Manager) Save(w http.ResponseWriter, r *http.Request, session *Session) error {
+	expiresAt := time.Now().Add(time.Duration(manager.config.MaxAgeInSec) * time.Second)
+	payload := cookiePayload{ExpiresAt: expiresAt.Unix()}
+	if manager.store == nil {
+		payload.Values = session.values
+	} else {
+		if session.id == "" {
+			session.id = newID()
+		}
+		if err := manager.store.Save(r.Context(), session.id, session.values, expiresAt); err != nil {
+			return fmt.Errorf("error saving session: %v", err)
+		}
+		payload.ID = session.id
+	}
+
+	plaintext, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding session: %v", err)
+	}
+	value, err := manager.codec.seal(manager.config.CookieName, plaintext)
+	if err != nil {
+		return fmt.Errorf("error sealing session: %v", err)
+	}
+	if len(value) > maxCookieSize {
+		return fmt.Errorf("session cookie is %d bytes, over the %d bytes browser limit: use a server-side store", len(value), maxCookieSize)
+	}
+	http.SetCookie(w, manager.cookie(value, manager.config.MaxAgeInSec))
+	return nil
+}
+
+// Login stores userID in the session of r. The session ID is rotated so a
+// session fixed before login can't be reused.
+func (manager *Manager) Login(w http.ResponseWriter, r *http.Request, userID string) error {
+	session, err := manager.Get(r)
+	if err != nil {
+		return err
+	}
+	if err := manager.deleteFromStore(r.Context(), session); err != nil {
+		return err
+	}
+	session.id = ""
+	session.Set(userIDKey, userID)
+	return manager.Save(w, r, session)
+}
+
+// Logout destroys the session of r and expires its cookie.
+func (manager *Manager) Logout(w http.ResponseWriter, r *http.Request) error {
+	session, err := manager.Get(r)
+	if err != nil {
+		return err
+	}
+	if err := manager.deleteFromStore(r.Context(), session); err != nil {
+		return err
+	}
+	http.SetCookie(w, manager.cookie("", -1))
+	return nil
+}
+
+func (manager *Manager) deleteFromStore(ctx context.Context, session *Session) error {
+	if manager.store == nil || session.id == "" {
+		return nil
+	}
+	if err := manager.store.Delete(ctx, session.id); err != nil {
+		return fmt.Errorf("error deleting session: %v", err)
+	}
+	return nil
+}
+
+func (manager *Manager) cookie(value string, maxAge int) *http.Cookie {
+	sameSite := http.SameSiteLaxMode
+	switch strings.ToLower(manager.config.SameSite) {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+	return &http.Cookie{
+		Name:     manager.config.CookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   manager.config.Secure,
+		HttpOnly: true,
+		SameSite: sameSite,
+	}
+}
+
+func newID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
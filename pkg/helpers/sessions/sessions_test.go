@@ -0,0 +1,164 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestCodecRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		encrypt   bool
+		plaintext string
+	}{
+		{name: "encrypted", encrypt: true, plaintext: `{"v":{"user_id":"42"},"e":1}`},
+		{name: "signed", encrypt: false, plaintext: `{"v":{"user_id":"42"},"e":1}`},
+		{name: "encrypted empty", encrypt: true, plaintext: ""},
+		{name: "signed empty", encrypt: false, plaintext: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			codec := newCodec(testSecret, test.encrypt)
+			sealed, err := codec.seal("gothic_session", []byte(test.plaintext))
+			if err != nil {
+				t.Fatalf("seal() error = %v", err)
+			}
+			if test.encrypt && test.plaintext != "" && strings.Contains(sealed, "user_id") {
+				t.Errorf("seal() leaked the plaintext: %s", sealed)
+			}
+			opened, err := codec.open("gothic_session", sealed)
+			if err != nil {
+				t.Fatalf("open() error = %v", err)
+			}
+			if string(opened) != test.plaintext {
+				t.Errorf("open() = %q, want %q", opened, test.plaintext)
+			}
+		})
+	}
+}
+
+func TestCodecRejectsTampering(t *testing.T) {
+	for _, encrypt := range []bool{true, false} {
+		sealer := newCodec(testSecret, encrypt)
+		sealed, err := sealer.seal("gothic_session", []byte(`{"v":{"user_id":"42"}}`))
+		if err != nil {
+			t.Fatalf("seal() error = %v", err)
+		}
+		tests := []struct {
+			name   string
+			cookie string
+			value  string
+			codec  codec
+		}{
+			{name: "flipped character", cookie: "gothic_session", value: flipMiddle(sealed), codec: sealer},
+			{name: "truncated", cookie: "gothic_session", value: sealed[:len(sealed)/2], codec: sealer},
+			{name: "empty", cookie: "gothic_session", value: "", codec: sealer},
+			{name: "other cookie name", cookie: "other", value: sealed, codec: sealer},
+			{name: "other secret", cookie: "gothic_session", value: sealed, codec: newCodec(strings.Repeat("x", 32), encrypt)},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				if _, err := test.codec.open(test.cookie, test.value); err == nil {
+					t.Errorf("open() accepted a tampered value (encrypt %v)", encrypt)
+				}
+			})
+		}
+	}
+}
+
+func TestSignedPayloadCanNotBeReplaced(t *testing.T) {
+	codec := newCodec(testSecret, false)
+	sealed, _ := codec.seal("gothic_session", []byte(`{"v":{"user_id":"42"}}`))
+	forged, _ := codec.seal("gothic_session", []byte(`{"v":{"user_id":"1"}}`))
+	_, signature, _ := strings.Cut(sealed, ".")
+	payload, _, _ := strings.Cut(forged, ".")
+	if _, err := codec.open("gothic_session", payload+"."+signature); err == nil {
+		t.Error("open() accepted a payload with the signature of another one")
+	}
+}
+
+func TestNewWithStoreSecret(t *testing.T) {
+	tests := []struct {
+		name       string
+		secret     string
+		localServe string
+		wantErr    bool
+	}{
+		{name: "valid secret", secret: testSecret},
+		{name: "short secret", secret: "too-short", wantErr: true},
+		{name: "missing secret", secret: "", wantErr: true},
+		{name: "missing secret with local serve", secret: "", localServe: "true"},
+		{name: "short secret with local serve", secret: "too-short", localServe: "true", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("LOCAL_SERVE", test.localServe)
+			_, err := NewWithStore(DefaultConfig, test.secret, nil)
+			if (err != nil) != test.wantErr {
+				t.Errorf("NewWithStore() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestManagerSaveAndGet(t *testing.T) {
+	tests := []struct {
+		name  string
+		store string
+	}{
+		{name: "cookie store", store: "cookie"},
+		{name: "memory store", store: "memory"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := DefaultConfig
+			config.Store = test.store
+			manager, err := New(config, testSecret)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/login", nil)
+			if err := manager.Login(recorder, request, "42"); err != nil {
+				t.Fatalf("Login() error = %v", err)
+			}
+
+			next := httptest.NewRequest(http.MethodGet, "/account", nil)
+			for _, cookie := range recorder.Result().Cookies() {
+				next.AddCookie(cookie)
+			}
+			session, err := manager.Get(next)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if session.UserID() != "42" {
+				t.Errorf("UserID() = %q, want 42", session.UserID())
+			}
+
+			tampered := httptest.NewRequest(http.MethodGet, "/account", nil)
+			for _, cookie := range recorder.Result().Cookies() {
+				cookie.Value = flipMiddle(cookie.Value)
+				tampered.AddCookie(cookie)
+			}
+			if session, _ := manager.Get(tampered); session.IsAuthenticated() {
+				t.Error("Get() authenticated a tampered cookie")
+			}
+		})
+	}
+}
+
+// flipMiddle changes a character in the middle of a base64 value. The last
+// one may only hold padding bits.
+func flipMiddle(value string) string {
+	middle := len(value) / 2
+	replacement := "A"
+	if value[middle] == 'A' {
+		replacement = "B"
+	}
+	return value[:middle] + replacement + value[middle+1:]
+}
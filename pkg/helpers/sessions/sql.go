@@ -0,0 +1,110 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// SQLDialect selects the query placeholders of an SQLStore.
+type SQLDialect int
+
+const (
+	// MySQL and SQLite use "?" placeholders.
+	MySQL SQLDialect = iota
+	SQLite
+	// Postgres uses "$1" placeholders.
+	Postgres
+)
+
+var tableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQLStore keeps sessions in a database table through database/sql, so the
+// app picks its own driver. Call CreateTable once to create the table.
+type SQLStore struct {
+	db      *sql.DB
+	table   string
+	dialect SQLDialect
+}
+
+func NewSQLStore(db *sql.DB, table string, dialect SQLDialect) (*SQLStore, error) {
+	if !tableNameRegex.MatchString(table) {
+		return nil, fmt.Errorf("invalid session table name %q", table)
+	}
+	return &SQLStore{db: db, table: table, dialect: dialect}, nil
+}
+
+// CreateTable creates the session table if it does not exist. Expiration is
+// stored as a unix timestamp to stay portable across databases.
+func (store *SQLStore) CreateTable(ctx context.Context) error {
+	_, err := store.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+store.table+
+		" (id VARCHAR(64) PRIMARY KEY, data TEXT NOT NULL, expires_at BIGINT NOT NULL)")
+	return err
+}
+
+func (store *SQLStore) Load(ctx context.Context, id string) (map[string]string, error) {
+	var data string
+	err := store.db.QueryRowContext(ctx,
+		"SELECT data FROM "+store.table+" WHERE id = "+store.placeholder(1)+" AND expires_at > "+store.placeholder(2),
+		id, time.Now().Unix(),
+	).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]string
+	if err := json.Unmarshal([]byte(data), &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Save replaces the session row inside a transaction, since upsert syntax
+// differs between databases.
+func (store *SQLStore) Save(ctx context.Context, id string, values map[string]string, expiresAt time.Time) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+store.table+" WHERE id = "+store.placeholder(1), id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO "+store.table+" (id, data, expires_at) VALUES ("+store.placeholder(1)+", "+store.placeholder(2)+", "+store.placeholder(3)+")",
+		id, string(data), expiresAt.Unix(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (store *SQLStore) Delete(ctx context.Context, id string) error {
+	_, err := store.db.ExecContext(ctx, "DELETE FROM "+store.table+" WHERE id = "+store.placeholder(1), id)
+	return err
+}
+
+// DeleteExpired removes expired sessions. Run it periodically, e.g. from an
+// OnStart hook goroutine.
+func (store *SQLStore) DeleteExpired(ctx context.Context) error {
+	_, err := store.db.ExecContext(ctx, "DELETE FROM "+store.table+" WHERE expires_at <= "+store.placeholder(1), time.Now().Unix())
+	return err
+}
+
+func (store *SQLStore) placeholder(position int) string {
+	if store.dialect == Postgres {
+		return "$" + strconv.Itoa(position)
+	}
+	return "?"
+}
@@ -0,0 +1,153 @@
+package sessions
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Store keeps session values on the server, keyed by session ID. Load returns
+// nil values for unknown or expired sessions.
+type Store interface {
+	Load(ctx context.Context, id string) (map[string]string, error)
+	Save(ctx context.Context, id string, values map[string]string, expiresAt time.Time) error
+	Delete(ctx context.Context, id string) error
+}
+
+type storedSession struct {
+	Values    map[string]string `json:"values"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+// MemoryStore keeps sessions in the process memory. They are lost on restart
+// and not shared between Lambda instances, so use it for development or
+// single instance servers.
+type MemoryStore struct {
+	mutex    sync.Mutex
+	sessions map[string]storedSession
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: map[string]storedSession{}}
+}
+
+func (store *MemoryStore) Load(ctx context.Context, id string) (map[string]string, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	session, exists := store.sessions[id]
+	if !exists {
+		return nil, nil
+	}
+	if time.Now().After(session.ExpiresAt) {
+		delete(store.sessions, id)
+		return nil, nil
+	}
+	return copyValues(session.Values), nil
+}
+
+func (store *MemoryStore) Save(ctx context.Context, id string, values map[string]string, expiresAt time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	now := time.Now()
+	// Sweep expired sessions so abandoned ones don't pile up
+	for storedID, session := range store.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(store.sessions, storedID)
+		}
+	}
+	store.sessions[id] = storedSession{Values: copyValues(values), ExpiresAt: expiresAt}
+	return nil
+}
+
+func (store *MemoryStore) Delete(ctx context.Context, id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.sessions, id)
+	return nil
+}
+
+// FileStore keeps one JSON file per session in a directory.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating session directory: %v", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (store *FileStore) Load(ctx context.Context, id string) (map[string]string, error) {
+	path, err := store.path(id)
+	if err != nil {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var session storedSession
+	if err := json.Unmarshal(content, &session); err != nil {
+		return nil, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		os.Remove(path)
+		return nil, nil
+	}
+	return session.Values, nil
+}
+
+func (store *FileStore) Save(ctx context.Context, id string, values map[string]string, expiresAt time.Time) error {
+	path, err := store.path(id)
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(storedSession{Values: values, ExpiresAt: expiresAt})
+	if err != nil {
+		return err
+	}
+	// Write then rename, so concurrent requests never read half a file
+	temporary := path + ".tmp"
+	if err := os.WriteFile(temporary, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temporary, path)
+}
+
+func (store *FileStore) Delete(ctx context.Context, id string) error {
+	path, err := store.path(id)
+	if err != nil {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path only accepts the hex IDs generated by the Manager, keeping file names
+// inside the store directory.
+func (store *FileStore) path(id string) (string, error) {
+	if _, err := hex.DecodeString(id); err != nil || id == "" {
+		return "", fmt.Errorf("invalid session ID")
+	}
+	return filepath.Join(store.dir, id+".json"), nil
+}
+
+func copyValues(values map[string]string) map[string]string {
+	copied := make(map[string]string, len(values))
+	for key, value := range values {
+		copied[key] = value
+	}
+	return copied
+}
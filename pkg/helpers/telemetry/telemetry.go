@@ -0,0 +1,97 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/felipegenef/gothicframework/pkg/helpers/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// TracerName is the instrumentation scope of every span created by Gothic.
+const TracerName = "github.com/felipegenef/gothicframework"
+
+// Config is the "telemetry" section of gothic-config.json.
+type Config struct {
+	// Enabled turns on the OTLP trace exporter. Access logs are always written.
+	Enabled     bool   `json:"enabled"`
+	ServiceName string `json:"serviceName"`
+	// OtlpEndpoint is the host:port of an OTLP/HTTP collector.
+	OtlpEndpoint string  `json:"otlpEndpoint"`
+	Insecure     bool    `json:"insecure"`
+	SampleRatio  float64 `json:"sampleRatio"`
+	LogLevel     string  `json:"logLevel"`
+}
+
+var DefaultConfig = Config{
+	Enabled:      false,
+	OtlpEndpoint: "localhost:4318",
+	Insecure:     true,
+	SampleRatio:  1,
+	LogLevel:     "info",
+}
+
+// LoadConfig reads the "telemetry" section of gothic-config.json. The service
+// name defaults to the project name.
+func LoadConfig(gothicConfig []byte) (Config, error) {
+	telemetry, err := config.Section(gothicConfig, "telemetry", DefaultConfig)
+	if err != nil {
+		return telemetry, err
+	}
+	if telemetry.ServiceName == "" {
+		telemetry.ServiceName, err = config.Section(gothicConfig, "projectName", "")
+	}
+	return telemetry, err
+}
+
+// NewLogger returns a JSON slog logger writing to stdout, where CloudWatch and
+// most log collectors expect it.
+func NewLogger(config Config) *slog.Logger {
+	level := slog.LevelInfo
+	switch config.LogLevel {
+	case "debug":
+		level = slog.LevelDebug
+	case "warn":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+}
+
+// Setup installs the global tracer provider exporting spans over OTLP/HTTP.
+// When telemetry is disabled it only installs the W3C propagators, leaving the
+// no-op tracer in place. The returned function flushes pending spans.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !config.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.OtlpEndpoint)}
+	if config.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating OTLP exporter: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(config.ServiceName),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -2,6 +2,7 @@ package helpers
 
 import (
 	"context"
+	"io"
 	"os"
 
 	templ "github.com/a-h/templ/cmd/templ/generatecmd"
@@ -15,7 +16,13 @@ func NewTemplHelper() TemplHelper {
 }
 
 func (t *TemplHelper) Render() error {
-	logger := NewLogger("error", false, os.Stdout)
+	return t.Generate(os.Stdout)
+}
+
+// Generate is Render with the templ errors written to output instead of
+// stdout. The returned error only counts them.
+func (t *TemplHelper) Generate(output io.Writer) error {
+	logger := NewLogger("error", false, output)
 
 	err := templ.Run(context.Background(), logger, templ.Arguments{})
 	if err != nil {
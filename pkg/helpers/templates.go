@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"sort"
+	"strings"
 	"text/template"
+
+	"github.com/felipegenef/gothicframework/pkg/helpers/securityheaders"
 )
 
 type InitCmdTemplateInfo struct {
@@ -43,12 +47,40 @@ type StageTemplateInfo struct {
 	Env                   []EnvValueInfo
 }
 
+// CachePolicyTemplateInfo describes the cache key of the CloudFront cache
+// policy in front of the server origin.
+type CachePolicyTemplateInfo struct {
+	QueryStringBehavior string
+	QueryStrings        []string
+	Headers             []string
+	Cookies             []string
+}
+
+// AddHeader adds name to the cache key headers unless it is already there.
+func (policy *CachePolicyTemplateInfo) AddHeader(name string) {
+	for _, header := range policy.Headers {
+		if strings.EqualFold(header, name) {
+			return
+		}
+	}
+	policy.Headers = append(policy.Headers, name)
+	sort.Strings(policy.Headers)
+}
+
 type SamYamlTemplateInfo struct {
-	Timeout           int
-	MemorySize        int
-	UsedTemplateName  string
-	ProjectName       string
-	StageTemplateInfo StageTemplateInfo
+	Timeout                    int
+	MemorySize                 int
+	UsedTemplateName           string
+	ProjectName                string
+	AppID                      string
+	GitSHA                     string
+	StageTemplateInfo          StageTemplateInfo
+	CachePolicy                CachePolicyTemplateInfo
+	EnableAcceptEncodingGzip   bool
+	EnableAcceptEncodingBrotli bool
+	SecurityHeaders            securityheaders.Config
+	// Sessions passes the SessionSecret parameter to the Lambda.
+	Sessions bool
 }
 type SamTomlTemplateInfo struct {
 	StackName string